@@ -0,0 +1,723 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/jakewnuk/rulecat/pkg/dedupe"
+	"github.com/jakewnuk/rulecat/pkg/derive"
+	"github.com/jakewnuk/rulecat/pkg/dialect"
+	"github.com/jakewnuk/rulecat/pkg/engine"
+	"github.com/jakewnuk/rulecat/pkg/expand"
+	"github.com/jakewnuk/rulecat/pkg/input"
+	"github.com/jakewnuk/rulecat/pkg/optimize"
+	"github.com/jakewnuk/rulecat/pkg/reform"
+	"github.com/jakewnuk/rulecat/pkg/rule"
+	"github.com/jakewnuk/rulecat/pkg/score"
+	"github.com/jakewnuk/rulecat/pkg/utils"
+	"github.com/jakewnuk/rulecat/pkg/validate"
+)
+
+// runFunc runs a mode with its positional arguments
+type runFunc func(env *environment, args []string) error
+
+// command is a mode of the CLI
+type command struct {
+	// name is the mode name given on the command line
+	name string
+	// summary describes the mode in the usage
+	summary string
+	// examples are shown in the usage and the mode help
+	examples []string
+	// format is set for modes that write rules in the syntax of --format
+	format bool
+	// notes are extra lines shown after the flags in the mode help
+	notes []string
+	// setup adds the flags of the mode and returns the function that runs it
+	setup func(fs *flag.FlagSet) runFunc
+}
+
+// findCommand looks up a mode by name
+//
+// Args:
+//
+//	name (string): Mode name
+//
+// Returns:
+//
+//	(command): Mode
+//	(bool): If the mode exists
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// commands are the modes of rulecat in the order of the usage
+//
+// # Positional arguments from earlier versions are still accepted in place of
+// the flags of each mode
+var commands = []command{
+	{
+		name:     "append",
+		summary:  "Creates append rules from text",
+		examples: []string{"stdin | rulecat append", "stdin | rulecat append --mode remove", "stdin | rulecat append --mode shift"},
+		format:   true,
+		setup: func(fs *flag.FlagSet) runFunc {
+			mode := fs.String("mode", "default", "Modify the operation (default, remove, shift)")
+			threads := threadsFlag(fs)
+			counting := countFlags(fs)
+			return func(env *environment, args []string) error {
+				if err := env.count(counting, env.dialect); err != nil {
+					return err
+				}
+				gen, err := rule.AppendGenerator(first(arg(args, 0), *mode))
+				if err != nil {
+					return err
+				}
+				return env.stream(gen, env.dialect, *threads)
+			}
+		},
+	},
+	{
+		name:     "prepend",
+		summary:  "Creates prepend rules from text",
+		examples: []string{"stdin | rulecat prepend", "stdin | rulecat prepend --mode remove", "stdin | rulecat prepend --mode shift"},
+		format:   true,
+		setup: func(fs *flag.FlagSet) runFunc {
+			mode := fs.String("mode", "default", "Modify the operation (default, remove, shift)")
+			threads := threadsFlag(fs)
+			counting := countFlags(fs)
+			return func(env *environment, args []string) error {
+				if err := env.count(counting, env.dialect); err != nil {
+					return err
+				}
+				gen, err := rule.PrependGenerator(first(arg(args, 0), *mode))
+				if err != nil {
+					return err
+				}
+				return env.stream(gen, env.dialect, *threads)
+			}
+		},
+	},
+	{
+		name:     "blank",
+		summary:  "Creates blank lines from text",
+		examples: []string{"stdin | rulecat blank"},
+		setup: func(fs *flag.FlagSet) runFunc {
+			return func(env *environment, args []string) error {
+				return rule.BlankLines(env.in, env.out)
+			}
+		},
+	},
+	{
+		name:     "cartesian",
+		summary:  "Create cartesian product of text and one or more rule files",
+		examples: []string{"stdin | rulecat cartesian --file [RULE-FILE]", "stdin | rulecat cartesian --file [RULE-FILE] [RULE-FILE]", "rulecat cartesian [RULE-FILE] [RULE-FILE] [RULE-FILE] --unique"},
+		format:   true,
+		setup: func(fs *flag.FlagSet) runFunc {
+			path := fs.String("file", "", "Rule file to place after each line of text")
+			counting := countFlags(fs)
+			return func(env *environment, args []string) error {
+				if err := env.count(counting, env.dialect); err != nil {
+					return err
+				}
+				names := args
+				if *path != "" {
+					names = append([]string{*path}, args...)
+				}
+				if len(names) == 0 {
+					return fmt.Errorf("%w: must provide a rule file with --file for cartesian mode", rule.ErrInvalidArgument)
+				}
+
+				// text comes first unless the first of several positional
+				// files is a rule file
+				text := env.in
+				fromFile := *path == "" && len(names) > 1 && names[0] != input.Stdin
+				if *path == "" && len(names) > 1 && !fromFile {
+					names = names[1:]
+				}
+
+				sources := make([]rule.Source, len(names))
+				for i, name := range names {
+					if name == input.Stdin {
+						return fmt.Errorf("%w: only the first file of a product can be stdin", rule.ErrInvalidArgument)
+					}
+					sources[i] = openSource(name)
+				}
+
+				counts, total, err := rule.ProductSize(sources)
+				if err != nil {
+					return err
+				}
+				if !fromFile {
+					fmt.Fprintf(os.Stderr, "Expected %s rules per line of text from %v rule lines\n", formatSize(total), counts)
+				} else {
+					fmt.Fprintf(os.Stderr, "Expected %s rules from %v rule lines\n", formatSize(total), counts)
+					if text, err = env.open(names[0]); err != nil {
+						return err
+					}
+					sources = sources[1:]
+				}
+				return rule.Product(text, env.out, sources, env.dialect)
+			}
+		},
+	},
+	{
+		name:     "chars",
+		summary:  "Creates custom rules per character from text",
+		examples: []string{"stdin | rulecat chars --rule [RULE]"},
+		setup: func(fs *flag.FlagSet) runFunc {
+			text := fs.String("rule", "", "Rule to place before each character")
+			threads := threadsFlag(fs)
+			counting := countFlags(fs)
+			return func(env *environment, args []string) error {
+				if err := env.count(counting, dialect.Hashcat{}); err != nil {
+					return err
+				}
+				gen, err := rule.CharsGenerator(first(arg(args, 0), *text))
+				if err != nil {
+					return err
+				}
+				return env.stream(gen, dialect.Hashcat{}, *threads)
+			}
+		},
+	},
+	{
+		name:     "insert",
+		summary:  "Creates insert rules from text",
+		examples: []string{"stdin | rulecat insert --index [START-INDEX]"},
+		format:   true,
+		setup: func(fs *flag.FlagSet) runFunc {
+			index := fs.Int("index", 0, "Position of the first character (0-35)")
+			threads := threadsFlag(fs)
+			counting := countFlags(fs)
+			return func(env *environment, args []string) error {
+				if err := env.count(counting, env.dialect); err != nil {
+					return err
+				}
+				i, err := intArg(args, 0, "start index", *index)
+				if err != nil {
+					return err
+				}
+				gen, err := rule.InsertGenerator(i)
+				if err != nil {
+					return err
+				}
+				return env.stream(gen, env.dialect, *threads)
+			}
+		},
+	},
+	{
+		name:     "overwrite",
+		summary:  "Creates overwrite rules from text",
+		examples: []string{"stdin | rulecat overwrite --index [START-INDEX]"},
+		format:   true,
+		setup: func(fs *flag.FlagSet) runFunc {
+			index := fs.Int("index", 0, "Position of the first character (0-35)")
+			threads := threadsFlag(fs)
+			counting := countFlags(fs)
+			return func(env *environment, args []string) error {
+				if err := env.count(counting, env.dialect); err != nil {
+					return err
+				}
+				i, err := intArg(args, 0, "start index", *index)
+				if err != nil {
+					return err
+				}
+				gen, err := rule.OverwriteGenerator(i)
+				if err != nil {
+					return err
+				}
+				return env.stream(gen, env.dialect, *threads)
+			}
+		},
+	},
+	{
+		name:     "toggle",
+		summary:  "Creates toggle rules from text",
+		examples: []string{"stdin | rulecat toggle --index [START-INDEX]"},
+		format:   true,
+		setup: func(fs *flag.FlagSet) runFunc {
+			index := fs.Int("index", 0, "Position of the first character (0-35)")
+			threads := threadsFlag(fs)
+			counting := countFlags(fs)
+			return func(env *environment, args []string) error {
+				if err := env.count(counting, env.dialect); err != nil {
+					return err
+				}
+				i, err := intArg(args, 0, "start index", *index)
+				if err != nil {
+					return err
+				}
+				gen, err := rule.ToggleGenerator(i)
+				if err != nil {
+					return err
+				}
+				return env.stream(gen, env.dialect, *threads)
+			}
+		},
+	},
+	{
+		name:     "leet",
+		summary:  "Creates leetspeak substitution rules from the characters of text",
+		examples: []string{"stdin | rulecat leet", "stdin | rulecat leet --mode all --limit 50", "stdin | rulecat leet --table [TABLE-FILE]"},
+		format:   true,
+		setup: func(fs *flag.FlagSet) runFunc {
+			mode := fs.String("mode", "full", "Kind of substitutions (full, partial, positional, all)")
+			table := fs.String("table", "", "File of mappings such as a=@4 with one character per line")
+			limit := fs.Int("limit", rule.DefaultLeetLimit, "Maximum number of rules per line of text")
+			threads := threadsFlag(fs)
+			counting := countFlags(fs)
+			return func(env *environment, args []string) error {
+				if err := env.count(counting, env.dialect); err != nil {
+					return err
+				}
+				mapping := rule.DefaultLeetTable
+				if *table != "" {
+					file, err := readFile(*table)
+					if err != nil {
+						return err
+					}
+					if mapping, err = rule.ParseLeetTable(file); err != nil {
+						return err
+					}
+				}
+				gen, err := rule.LeetGenerator(mapping, *mode, *limit)
+				if err != nil {
+					return err
+				}
+				return env.stream(gen, env.dialect, *threads)
+			}
+		},
+	},
+	{
+		name:     "encode",
+		summary:  "Encodes input with URL, HTML, Unicode escape, or other codecs and prints new output",
+		examples: []string{"stdin | rulecat encode", "stdin | rulecat encode --with url,base64,hex", "stdin | rulecat encode --hex", "stdin | rulecat encode --rules"},
+		format:   true,
+		notes:    codecNotes("(default)", func(c reform.Codec) bool { return slices.Contains(reform.DefaultCodecs, c.Name) }),
+		setup: func(fs *flag.FlagSet) runFunc {
+			with := fs.String("with", "", "Comma separated codecs to encode with from the list below")
+			hex := fs.Bool("hex", false, "Also write each line as $HEX[...] and write output that is not printable as $HEX[...]")
+			rules := fs.Bool("rules", false, "Write the rule that transforms each line into its encoding instead of the encoded text")
+			threads := threadsFlag(fs)
+			counting := countFlags(fs)
+			return func(env *environment, args []string) error {
+				if *rules && *hex {
+					return fmt.Errorf("%w: --rules and --hex cannot be used together", rule.ErrInvalidArgument)
+				}
+				d := dialect.Dialect(dialect.Hashcat{})
+				if *rules {
+					d = env.dialect
+				}
+				if err := env.count(counting, d); err != nil {
+					return err
+				}
+				codecs, err := reform.Select(*with)
+				if err != nil {
+					return fmt.Errorf("%w: %w", rule.ErrInvalidArgument, err)
+				}
+				gen := func(word string) []string { return reform.EncodeWith(word, codecs) }
+				if *rules {
+					gen = func(word string) []string { return derive.EncodingRules(word, reform.EncodeWith(word, codecs)) }
+				}
+				if *hex {
+					gen = func(word string) []string {
+						encoded := []string{utils.EncodeHexString(word)}
+						for _, e := range reform.EncodeWith(word, codecs) {
+							encoded = append(encoded, utils.FormatPlaintext(e))
+						}
+						return encoded
+					}
+				}
+				return env.stream(gen, d, *threads)
+			}
+		},
+	},
+	{
+		name:     "decode",
+		summary:  "Decodes URL, HTML, Unicode escape, base64, or hex encoded input and prints the original text",
+		examples: []string{"stdin | rulecat decode", "stdin | rulecat decode --with base64", "stdin | rulecat decode --strict"},
+		notes:    codecNotes("(detected)", func(c reform.Codec) bool { return c.Detect != nil }),
+		setup: func(fs *flag.FlagSet) runFunc {
+			with := fs.String("with", "", "Comma separated codecs from the list below to try in order instead of detecting the encoding")
+			strict := fs.Bool("strict", false, "Drop lines that cannot be decoded instead of printing them unchanged")
+			threads := threadsFlag(fs)
+			counting := countFlags(fs)
+			return func(env *environment, args []string) error {
+				if err := env.count(counting, dialect.Hashcat{}); err != nil {
+					return err
+				}
+				var codecs []reform.Codec
+				if *with != "" {
+					var err error
+					if codecs, err = reform.Select(*with); err != nil {
+						return fmt.Errorf("%w: %w", rule.ErrInvalidArgument, err)
+					}
+				}
+				gen := func(word string) []string {
+					decoded, ok := reform.Decode(word, codecs)
+					if !ok && *strict {
+						return nil
+					}
+					return []string{utils.FormatPlaintext(decoded)}
+				}
+				return env.stream(gen, dialect.Hashcat{}, *threads)
+			}
+		},
+	},
+	{
+		name:     "combo",
+		summary:  "Combines multiple modes into one rule per line (toggle, prepend, append, insert)",
+		examples: []string{"stdin | rulecat combo [MODE-A] [MODE-B]"},
+		setup: func(fs *flag.FlagSet) runFunc {
+			threads := threadsFlag(fs)
+			counting := countFlags(fs)
+			return func(env *environment, args []string) error {
+				if err := env.count(counting, dialect.Hashcat{}); err != nil {
+					return err
+				}
+				if len(args) < 2 {
+					return fmt.Errorf("%w: must provide 2 arguments for combo mode (toggle, prepend, append, insert)", rule.ErrInvalidArgument)
+				}
+				gen, err := rule.ComboGenerator(args[0], args[1])
+				if err != nil {
+					return err
+				}
+				return env.stream(gen, dialect.Hashcat{}, *threads)
+			}
+		},
+	},
+	{
+		name:     "apply",
+		summary:  "Applies rules from a file to text and prints the candidates",
+		examples: []string{"stdin | rulecat apply --rules [RULE-FILE]"},
+		setup: func(fs *flag.FlagSet) runFunc {
+			rules := fs.String("rules", "", "Rule file to apply")
+			hex := fs.Bool("hex", false, "Write candidates that are not printable as $HEX[...]")
+			return func(env *environment, args []string) error {
+				name := first(arg(args, 0), *rules)
+				if name == "" {
+					return fmt.Errorf("%w: must provide a rule file with --rules for apply mode", rule.ErrInvalidArgument)
+				}
+				file, err := readFile(name)
+				if err != nil {
+					return err
+				}
+				engine.ApplyRules(env.scanWords(env.in), env.out, file, *hex)
+				return nil
+			}
+		},
+	},
+	{
+		name:     "validate",
+		summary:  "Checks rules against Hashcat or John the Ripper limits and reports problems per line",
+		examples: []string{"stdin | rulecat validate", "rulecat validate --input [RULE-FILE]", "rulecat validate --input [RULE-FILE] --clean", "rulecat validate --input [RULE-FILE] --format john"},
+		format:   true,
+		setup: func(fs *flag.FlagSet) runFunc {
+			clean := fs.Bool("clean", false, "Print only valid lines and report problems to stderr")
+			return func(env *environment, args []string) error {
+				input := env.in
+				var names []string
+				for _, a := range args {
+					if a == "clean" {
+						*clean = true
+						continue
+					}
+					names = append(names, a)
+				}
+				if len(names) > 0 {
+					file, err := env.open(names...)
+					if err != nil {
+						return err
+					}
+					input = file
+				}
+				check := validate.CheckRule
+				if env.dialect.Name() == "john" {
+					check = validate.CheckJohn
+				}
+				invalid := validate.ValidateRules(env.scan(input), env.out, *clean, check)
+				if invalid > 0 && !*clean {
+					return fmt.Errorf("%d invalid rule lines", invalid)
+				}
+				return nil
+			}
+		},
+	},
+	{
+		name:     "derive",
+		summary:  "Creates the rule that transforms a base word into a password",
+		examples: []string{"stdin (base:password) | rulecat derive", "rulecat derive --input [BASE-FILE] --passwords [PASSWORD-FILE]"},
+		setup: func(fs *flag.FlagSet) runFunc {
+			passwords := fs.String("passwords", "", "Passwords matching each base word line")
+			return func(env *environment, args []string) error {
+				bases := env.in
+				if len(args) > 0 {
+					file, err := env.open(args[0])
+					if err != nil {
+						return err
+					}
+					bases = file
+				}
+
+				name := first(arg(args, 1), *passwords)
+				if name == "" {
+					if len(args) > 0 {
+						return fmt.Errorf("%w: must provide a password file with --passwords for derive mode", rule.ErrInvalidArgument)
+					}
+					derive.DeriveRules(env.scanWords(bases), nil, env.out)
+					return nil
+				}
+				plains, err := env.open(name)
+				if err != nil {
+					return err
+				}
+				derive.DeriveRules(env.scanWords(bases), env.scanWords(plains), env.out)
+				return nil
+			}
+		},
+	},
+	{
+		name:     "learn-subs",
+		summary:  "Counts the character substitutions between base words and passwords",
+		examples: []string{"stdin (base:password) | rulecat learn-subs", "stdin (password) | rulecat learn-subs --dictionary [WORD-FILE] --rules", "rulecat learn-subs --input [BASE-FILE] --passwords [PASSWORD-FILE]"},
+		format:   true,
+		setup: func(fs *flag.FlagSet) runFunc {
+			passwords := fs.String("passwords", "", "Passwords matching each base word line")
+			dictionary := fs.String("dictionary", "", "Words to find the base word of each password line in")
+			rules := fs.Bool("rules", false, "Write sXY rules ordered by frequency instead of counts")
+			minCount := fs.Int("min-count", 1, "Write substitutions seen at least this many times")
+			return func(env *environment, args []string) error {
+				if *passwords != "" && *dictionary != "" {
+					return fmt.Errorf("%w: --passwords and --dictionary cannot be used together", rule.ErrInvalidArgument)
+				}
+
+				var learner *derive.Learner
+				switch {
+				case *dictionary != "":
+					words, err := readWords(*dictionary)
+					if err != nil {
+						return err
+					}
+					learner = derive.LearnFromDictionary(env.scanWords(env.in), derive.NewDictionary(words))
+				case *passwords != "":
+					plains, err := env.open(*passwords)
+					if err != nil {
+						return err
+					}
+					learner = derive.LearnSubstitutions(env.scanWords(env.in), env.scanWords(plains))
+				default:
+					learner = derive.LearnSubstitutions(env.scanWords(env.in), nil)
+				}
+				derive.WriteSubstitutions(env.out, learner.Substitutions(*minCount), *rules, env.dialect)
+				return nil
+			}
+		},
+	},
+	{
+		name:     "dedupe",
+		summary:  "Removes rules that behave the same keeping the first seen",
+		examples: []string{"stdin | rulecat dedupe", "stdin | rulecat dedupe --probes [PROBE-FILE]"},
+		setup: func(fs *flag.FlagSet) runFunc {
+			probeFile := fs.String("probes", "", "Words to confirm rules behave the same")
+			return func(env *environment, args []string) error {
+				var probes []string
+				if name := first(arg(args, 0), *probeFile); name != "" {
+					var err error
+					if probes, err = readWords(name); err != nil {
+						return err
+					}
+				}
+				dedupe.DedupeRules(env.scan(env.in), env.out, probes)
+				return nil
+			}
+		},
+	},
+	{
+		name:     "optimize",
+		summary:  "Rewrites rules into shorter equivalent forms",
+		examples: []string{"stdin | rulecat optimize"},
+		setup: func(fs *flag.FlagSet) runFunc {
+			return func(env *environment, args []string) error {
+				optimize.OptimizeRules(env.scan(env.in), env.out)
+				return nil
+			}
+		},
+	},
+	{
+		name:     "convert",
+		summary:  "Converts rule files between Hashcat and John the Ripper syntax",
+		examples: []string{"rulecat convert --from hashcat --to john --input [RULE-FILE]", "stdin | rulecat convert --from john --to hashcat"},
+		setup: func(fs *flag.FlagSet) runFunc {
+			from := fs.String("from", "hashcat", "Syntax of the input rules (hashcat, john)")
+			to := fs.String("to", "john", "Syntax of the output rules (hashcat, john)")
+			return func(env *environment, args []string) error {
+				input := env.in
+				if len(args) > 0 {
+					file, err := env.open(args[0])
+					if err != nil {
+						return err
+					}
+					input = file
+				}
+				file, err := io.ReadAll(input)
+				if err != nil {
+					return rule.ReadError(err, 0)
+				}
+				fromDialect, err := dialect.New(*from)
+				if err != nil {
+					return fmt.Errorf("%w: %w", rule.ErrInvalidArgument, err)
+				}
+				toDialect, err := dialect.New(*to)
+				if err != nil {
+					return fmt.Errorf("%w: %w", rule.ErrInvalidArgument, err)
+				}
+				if failed := dialect.ConvertRules(file, env.out, fromDialect, toDialect); failed > 0 {
+					return fmt.Errorf("%d rule lines could not be converted", failed)
+				}
+				return nil
+			}
+		},
+	},
+	{
+		name:     "expand",
+		summary:  "Expands John the Ripper preprocessor ranges into Hashcat rules",
+		examples: []string{"stdin | rulecat expand", "stdin | rulecat expand --count"},
+		setup: func(fs *flag.FlagSet) runFunc {
+			count := fs.Bool("count", false, "Print only the number of rules that would be created")
+			return func(env *environment, args []string) error {
+				expand.ExpandRules(env.scan(env.in), env.out, *count || arg(args, 0) == "count")
+				return nil
+			}
+		},
+	},
+	{
+		name:     "score",
+		summary:  "Counts the passwords each rule cracks from base words as hits and unique hits",
+		examples: []string{"stdin | rulecat score --rules [RULE-FILE] --plains [PLAINTEXT-FILE]", "stdin | rulecat score --rules [RULE-FILE] --plains [PLAINTEXT-FILE] --sort unique --min 5"},
+		setup: func(fs *flag.FlagSet) runFunc {
+			rules := fs.String("rules", "", "Rule file to score")
+			plains := fs.String("plains", "", "Known plaintexts to count as hits")
+			sortBy := fs.String("sort", "", "Sort by hits or unique in descending order (hits, unique)")
+			threshold := fs.Int("min", 1, "Minimum value of the sort column or hits for a rule to be printed")
+			return func(env *environment, args []string) error {
+				// positional form is [RULE-FILE] [PLAINTEXT-FILE] [hits|unique] [MIN]
+				for i := 2; i < len(args); i++ {
+					if args[i] == "hits" || args[i] == "unique" {
+						*sortBy = args[i]
+						continue
+					}
+					n, err := intArg(args, i, "threshold", *threshold)
+					if err != nil {
+						return err
+					}
+					*threshold = n
+				}
+				if *sortBy != "" && *sortBy != "hits" && *sortBy != "unique" {
+					return fmt.Errorf("%w: sort %q must be hits or unique", rule.ErrInvalidArgument, *sortBy)
+				}
+
+				ruleFile, plainFile, err := readRulesAndPlains(first(arg(args, 0), *rules), first(arg(args, 1), *plains), "score")
+				if err != nil {
+					return err
+				}
+				score.ScoreRules(env.scanWords(env.in), env.out, ruleFile, plainFile, *sortBy, *threshold)
+				return nil
+			}
+		},
+	},
+	{
+		name:     "order",
+		summary:  "Sorts rules so each next rule cracks the most new passwords from base words",
+		examples: []string{"stdin | rulecat order --rules [RULE-FILE] --plains [PLAINTEXT-FILE]", "stdin | rulecat order --rules [RULE-FILE] --plains [PLAINTEXT-FILE] --top 100"},
+		setup: func(fs *flag.FlagSet) runFunc {
+			rules := fs.String("rules", "", "Rule file to order")
+			plains := fs.String("plains", "", "Known plaintexts to count as hits")
+			top := fs.Int("top", 0, "Keep only the first N rules")
+			return func(env *environment, args []string) error {
+				if *top < 0 {
+					return fmt.Errorf("%w: --top %d must not be negative", rule.ErrInvalidArgument, *top)
+				}
+				ruleFile, plainFile, err := readRulesAndPlains(first(arg(args, 0), *rules), first(arg(args, 1), *plains), "order")
+				if err != nil {
+					return err
+				}
+				score.OrderRules(env.scanWords(env.in), env.out, ruleFile, plainFile, *top)
+				return nil
+			}
+		},
+	},
+}
+
+// readRulesAndPlains reads the rule and plaintext files of score and order
+//
+// Args:
+//
+//	rules (string): Path of the rule file
+//	plains (string): Path of the plaintext file
+//	mode (string): Mode name for errors
+//
+// Returns:
+//
+//	([]byte): Rule file contents
+//	([]string): Plaintexts with $HEX[...] lines decoded
+//	(error): Error if a path is missing or a file cannot be read
+func readRulesAndPlains(rules string, plains string, mode string) ([]byte, []string, error) {
+	if rules == "" || plains == "" {
+		return nil, nil, fmt.Errorf("%w: must provide --rules and --plains files for %s mode", rule.ErrInvalidArgument, mode)
+	}
+	ruleFile, err := readFile(rules)
+	if err != nil {
+		return nil, nil, err
+	}
+	plainLines, err := readWords(plains)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ruleFile, plainLines, nil
+}
+
+// openSource creates a product source that reads a file from disk each time
+func openSource(name string) rule.Source {
+	return func() (io.ReadCloser, error) { return input.Open(name) }
+}
+
+// formatSize formats a product size that may have overflowed
+func formatSize(n uint64) string {
+	if n == math.MaxUint64 {
+		return "more than " + strconv.FormatUint(n, 10)
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+// codecNotes lists the registered codecs and their descriptions for the
+// encode and decode help
+//
+// Args:
+//
+//	label (string): Text added to the description of marked codecs
+//	marked (func(reform.Codec) bool): Reports if a codec is labeled
+//
+// Returns:
+//
+//	([]string): Lines of the list
+func codecNotes(label string, marked func(c reform.Codec) bool) []string {
+	notes := []string{"Codecs:"}
+	for _, c := range reform.Codecs() {
+		if marked(c) {
+			c.Description += " " + label
+		}
+		notes = append(notes, fmt.Sprintf("  %-14s%s", c.Name, c.Description))
+	}
+	return notes
+}
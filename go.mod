@@ -10,3 +10,13 @@ retract (
 	v1.0.1
 	v1.0.0
 )
+
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/ulikunitz/xz v0.5.17
+)
+
+require (
+	golang.org/x/net v0.35.0
+	golang.org/x/text v0.22.0 // indirect
+)
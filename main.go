@@ -3,107 +3,584 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 
-	"github.com/jakewnuk/rulecat/pkg/reform"
+	"github.com/jakewnuk/rulecat/pkg/dialect"
+	"github.com/jakewnuk/rulecat/pkg/freq"
+	"github.com/jakewnuk/rulecat/pkg/input"
 	"github.com/jakewnuk/rulecat/pkg/rule"
+	"github.com/jakewnuk/rulecat/pkg/unique"
 )
 
 var version = "0.0.2"
 
 func main() {
+	args := os.Args[1:]
+	if len(args) == 0 {
+		printUsage()
+		os.Exit(0)
+	}
 
-	if len(os.Args) <= 1 {
+	switch args[0] {
+	case "--version", "-version", "version":
+		fmt.Printf("rulecat version %s\n", version)
+		os.Exit(0)
+	case "--help", "-help", "-h", "help":
+		if len(args) > 1 {
+			if cmd, ok := findCommand(args[1]); ok {
+				fs := newFlagSet(cmd, &options{})
+				cmd.setup(fs)
+				printCommandUsage(cmd, fs)
+				os.Exit(0)
+			}
+		}
 		printUsage()
 		os.Exit(0)
 	}
 
-	stdIn := bufio.NewScanner(os.Stdin)
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		// a rule file in place of a mode is the original cartesian syntax
+		if _, err := os.Stat(args[0]); err != nil {
+			fail(fmt.Errorf("%w: unknown mode %q, see rulecat --help", rule.ErrInvalidArgument, args[0]))
+		}
+		cmd, _ = findCommand("cartesian")
+	} else {
+		args = args[1:]
+	}
+
+	opts := &options{}
+	fs := newFlagSet(cmd, opts)
+	run := cmd.setup(fs)
+	positional, err := parseArgs(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		printCommandUsage(cmd, fs)
+		os.Exit(0)
+	}
+	if err != nil {
+		fail(fmt.Errorf("%w: %w, see rulecat %s --help", rule.ErrInvalidArgument, err, cmd.name))
+	}
 
-	_, err := os.Stat(os.Args[1])
+	env, err := opts.environment()
+	if err != nil {
+		fail(err)
+	}
+	err = run(env, positional)
 	if err == nil {
-		file, err := os.ReadFile(os.Args[1])
+		err = env.scanErr()
+	}
+	finish(env, err)
+	if err := env.close(); err != nil {
+		fail(err)
+	}
+}
+
+// outputBufferSize is the size of the buffer in front of the output
+const outputBufferSize = 64 * 1024
+
+// options are the flags shared by every mode
+type options struct {
+	input  sources
+	output string
+	format string
+	// unique removes repeated output lines
+	unique bool
+	// uniqueRate is the false positive rate of a Bloom filter or zero for an
+	// exact filter
+	uniqueRate float64
+	// uniqueSize is the expected number of unique lines for a Bloom filter
+	uniqueSize uint64
+}
+
+// environment is the input, output, and syntax a mode runs with
+type environment struct {
+	// in is the input of the mode
+	in io.Reader
+	// out is the buffered output of the mode
+	out *bufio.Writer
+	// dialect is the syntax to write rules in
+	dialect dialect.Dialect
+	// scanners are checked for read errors after the mode finishes
+	scanners []*bufio.Scanner
+	// files are closed after the mode finishes
+	files []io.Closer
+	// output is the output file or nil for stdout
+	output *os.File
+	// flushers are flushed in order after the mode finishes, starting with
+	// out
+	flushers []flusher
+}
+
+// flusher is a writer that holds output until it is flushed
+type flusher interface {
+	Flush() error
+}
+
+// newFlagSet creates the flag set for a mode with the shared flags
+//
+// Args:
+//
+//	cmd (command): Mode to create the flags for
+//	opts (*options): Destination for the shared flags
+//
+// Returns:
+//
+//	(*flag.FlagSet): Flags of the mode
+func newFlagSet(cmd command, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	fs.Var(&opts.input, "input", "Read input from files, directories, glob patterns, or - for stdin (repeatable)")
+	fs.StringVar(&opts.output, "output", "", "Write output to a file instead of stdout")
+	if cmd.format {
+		fs.StringVar(&opts.format, "format", "hashcat", "Write rules in this syntax (hashcat, john)")
+	}
+	fs.BoolVar(&opts.unique, "unique", false, "Remove repeated output lines keeping the first seen")
+	fs.Float64Var(&opts.uniqueRate, "unique-fp", 0, "Use a Bloom filter with this false positive rate for --unique")
+	fs.Uint64Var(&opts.uniqueSize, "unique-size", 100_000_000, "Expected number of unique lines for the Bloom filter")
+	return fs
+}
+
+// sources are the repeatable --input values
+type sources []string
+
+// String returns the sources as a comma separated list
+func (s *sources) String() string {
+	return strings.Join(*s, ",")
+}
+
+// Set adds a source from a --input flag
+func (s *sources) Set(value string) error {
+	if value == "" {
+		return errors.New("input must not be empty")
+	}
+	*s = append(*s, value)
+	return nil
+}
+
+// parseArgs parses flags that may appear before or after positional
+// arguments
+//
+// Args:
+//
+//	fs (*flag.FlagSet): Flags of the mode
+//	args ([]string): Arguments after the mode name
+//
+// Returns:
+//
+//	([]string): Positional arguments in order
+//	(error): Error if a flag is unknown or has an invalid value
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// environment opens the input and output of a mode
+//
+// Returns:
+//
+//	(*environment): Environment for the mode
+//	(error): Error if a file cannot be opened or the format is unknown
+func (o *options) environment() (*environment, error) {
+	d, err := dialect.New(o.format)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", rule.ErrInvalidArgument, err)
+	}
+
+	env := &environment{in: os.Stdin, dialect: d}
+	if len(o.input) > 0 {
+		r, err := env.open(o.input...)
 		if err != nil {
-			fmt.Printf("ERROR: %s\n", err)
-			os.Exit(1)
+			return nil, err
 		}
-		rule.CartesianRules(stdIn, file)
-		os.Exit(0)
+		env.in = r
 	}
 
-	switch os.Args[1] {
-	case "append":
-		// if no mode use default
-		if len(os.Args) == 2 {
-			os.Args = append(os.Args, "default")
+	var out io.Writer = os.Stdout
+	if o.output != "" && o.output != "-" {
+		file, err := os.Create(o.output)
+		if err != nil {
+			return nil, rule.WriteError(err)
 		}
-		rule.AppendRules(stdIn, os.Args[2])
-	case "prepend":
-		// if no mode use default
-		if len(os.Args) == 2 {
-			os.Args = append(os.Args, "default")
+		env.output = file
+		out = file
+	}
+
+	if o.unique || o.uniqueRate != 0 {
+		var filter unique.Filter = unique.NewExact()
+		if o.uniqueRate != 0 {
+			bloom, err := unique.NewBloom(o.uniqueSize, o.uniqueRate)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", rule.ErrInvalidArgument, err)
+			}
+			filter = bloom
 		}
-		rule.PrependRules(stdIn, os.Args[2])
-	case "insert":
-		if len(os.Args) == 2 {
-			os.Args = append(os.Args, "0")
+		u := unique.NewWriter(out, filter)
+		env.flushers = append(env.flushers, u)
+		out = u
+	}
+	env.out = bufio.NewWriterSize(out, outputBufferSize)
+	env.flushers = append([]flusher{env.out}, env.flushers...)
+	return env, nil
+}
+
+// open opens files that are closed after the mode finishes
+//
+// # Compressed files are decompressed as they are read
+//
+// Args:
+//
+//	paths (...string): Files, directories, glob patterns, or "-"
+//
+// Returns:
+//
+//	(io.Reader): Text of every file in order
+//	(error): ErrRead if a file cannot be found
+func (e *environment) open(paths ...string) (io.Reader, error) {
+	r, err := input.Open(paths...)
+	if err != nil {
+		return nil, rule.ReadError(err, 0)
+	}
+	e.files = append(e.files, r)
+	return r, nil
+}
+
+// scan creates a line scanner that is checked for errors after the mode
+// finishes
+//
+// Args:
+//
+//	r (io.Reader): Input to scan
+//
+// Returns:
+//
+//	(*bufio.Scanner): Line scanner
+func (e *environment) scan(r io.Reader) *bufio.Scanner {
+	s := bufio.NewScanner(r)
+	e.scanners = append(e.scanners, s)
+	return s
+}
+
+// scanWords creates a line scanner for words and passwords that decodes
+// lines in the $HEX[...] format
+//
+// Args:
+//
+//	r (io.Reader): Input to scan
+//
+// Returns:
+//
+//	(*bufio.Scanner): Line scanner
+func (e *environment) scanWords(r io.Reader) *bufio.Scanner {
+	s := e.scan(r)
+	s.Split(input.ScanHexLines)
+	return s
+}
+
+// stream runs a generator over each line of the input and writes the
+// output in input order
+//
+// # Lines in the $HEX[...] format are decoded before the generator runs
+//
+// Args:
+//
+//	gen (rule.Generator): Generator to run on each line
+//	d (dialect.Dialect): Syntax to write the output in
+//	threads (int): Number of worker goroutines
+//
+// Returns:
+//
+//	(error): ErrInvalidArgument if threads is less than one or an error from
+//	rule.StreamParallel
+func (e *environment) stream(gen rule.Generator, d dialect.Dialect, threads int) error {
+	if threads < 1 {
+		return fmt.Errorf("%w: threads must be at least 1", rule.ErrInvalidArgument)
+	}
+	words := func(word string) []string { return gen(input.DecodeLine(word)) }
+	return rule.StreamParallel(e.in, e.out, words, d, threads)
+}
+
+// countOptions are the flags of the modes that can count repeated rules
+type countOptions struct {
+	counts bool
+	sortBy string
+	min    int
+}
+
+// countFlags registers the flags that count repeated rules
+func countFlags(fs *flag.FlagSet) *countOptions {
+	c := &countOptions{}
+	fs.BoolVar(&c.counts, "count", false, "Write each rule once as count<TAB>rule")
+	fs.StringVar(&c.sortBy, "sort", "", "Write each rule once sorted by this key (freq)")
+	fs.IntVar(&c.min, "min-count", 0, "Write each rule once if it is seen at least this many times")
+	return c
+}
+
+// count places a writer in front of the output that counts repeated rules
+// if any counting flag is set
+//
+// Args:
+//
+//	c (*countOptions): Counting flags of the mode
+//	d (dialect.Dialect): Syntax the mode writes rules in
+//
+// Returns:
+//
+//	(error): ErrInvalidArgument if a flag value is not valid
+func (e *environment) count(c *countOptions, d dialect.Dialect) error {
+	if !c.counts && c.sortBy == "" && c.min == 0 {
+		return nil
+	}
+	if c.sortBy != "" && c.sortBy != "freq" {
+		return fmt.Errorf("%w: unknown sort %q, must be freq", rule.ErrInvalidArgument, c.sortBy)
+	}
+	if c.min < 0 {
+		return fmt.Errorf("%w: min count must not be negative", rule.ErrInvalidArgument)
+	}
+
+	f := freq.NewWriter(e.out, freq.Options{Sort: c.sortBy == "freq", Counts: c.counts, Min: c.min, Header: d.Header()})
+	e.out = bufio.NewWriterSize(f, outputBufferSize)
+	e.flushers = append([]flusher{e.out, f}, e.flushers...)
+	return nil
+}
+
+// threadsFlag registers the worker count flag of the line-wise modes
+func threadsFlag(fs *flag.FlagSet) *int {
+	return fs.Int("threads", 1, "Number of worker goroutines that process lines in parallel")
+}
+
+// scanErr returns the first read error of the mode's scanners
+func (e *environment) scanErr() error {
+	for _, s := range e.scanners {
+		if err := s.Err(); err != nil {
+			return rule.ReadError(err, 0)
 		}
-		rule.InsertRules(stdIn, os.Args[2])
-	case "overwrite":
-		if len(os.Args) == 2 {
-			os.Args = append(os.Args, "0")
+	}
+	return nil
+}
+
+// close closes the files opened for the mode
+func (e *environment) close() error {
+	for _, file := range e.files {
+		file.Close()
+	}
+	if e.output != nil {
+		if err := e.output.Close(); err != nil {
+			return rule.WriteError(err)
 		}
-		rule.OverwriteRules(stdIn, os.Args[2])
-	case "toggle":
-		if len(os.Args) == 2 {
-			os.Args = append(os.Args, "0")
+	}
+	return nil
+}
+
+// readFile reads a whole file named by a mode option
+//
+// Args:
+//
+//	path (string): Path of the file
+//
+// Returns:
+//
+//	([]byte): Decompressed file contents
+//	(error): ErrRead if the file cannot be read
+func readFile(path string) ([]byte, error) {
+	file, err := input.ReadFile(path)
+	if err != nil {
+		return nil, rule.ReadError(err, 0)
+	}
+	return file, nil
+}
+
+// readWords reads the non-empty lines of a word or password file named by a
+// mode option and decodes lines in the $HEX[...] format
+//
+// Args:
+//
+//	path (string): Path of the file
+//
+// Returns:
+//
+//	([]string): Decoded lines
+//	(error): ErrRead if the file cannot be read
+func readWords(path string) ([]string, error) {
+	file, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return input.SplitWords(file), nil
+}
+
+// arg returns a positional argument or an empty string if it is missing
+func arg(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
+
+// first returns the first value that is not empty
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
 		}
-		rule.ToggleRules(stdIn, os.Args[2])
-	case "blank":
-		rule.BlankLines(stdIn)
-	case "chars":
-		rule.CharsToRules(stdIn, os.Args[2])
-	case "encode":
-		reform.EncodeInput(stdIn)
-	case "combo":
-		if len(os.Args) < 4 {
-			fmt.Println("ERROR: Must provide 2 arguments for combo mode (toggle, prepend, append, insert)")
-			os.Exit(0)
+	}
+	return ""
+}
+
+// intArg reads a number from a positional argument or falls back to a flag
+//
+// Args:
+//
+//	args ([]string): Positional arguments
+//	i (int): Index of the argument
+//	name (string): Name of the value for errors
+//	value (int): Flag value used if the argument is missing
+//
+// Returns:
+//
+//	(int): Number
+//	(error): ErrInvalidArgument if the argument is not a number
+func intArg(args []string, i int, name string, value int) (int, error) {
+	if i >= len(args) {
+		return value, nil
+	}
+	n, err := strconv.Atoi(args[i])
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s %q must be a number", rule.ErrInvalidArgument, name, args[i])
+	}
+	return n, nil
+}
+
+// finish flushes buffered output and exits if a mode failed
+//
+// # Output written before a failure is still flushed
+//
+// Args:
+//
+//	env (*environment): Environment the mode ran with
+//	err (error): Error returned by the mode
+//
+// Returns:
+//
+//	None
+func finish(env *environment, err error) {
+	for _, f := range env.flushers {
+		if flushErr := f.Flush(); flushErr != nil {
+			if err == nil {
+				err = rule.WriteError(flushErr)
+			}
+			break
 		}
-		rule.ComboRules(stdIn, os.Args[2], os.Args[3])
+	}
+	if err != nil {
+		fail(err)
+	}
+}
 
-	default:
-		printUsage()
-		os.Exit(0)
+// Exit codes used when a mode fails
+const (
+	exitError       = 1
+	exitInvalidArgs = 2
+	exitRead        = 3
+	exitLineTooLong = 4
+	exitWrite       = 5
+)
+
+// fail prints an error to stderr and exits with the code for its kind
+//
+// Args:
+//
+//	err (error): Error to report
+//
+// Returns:
+//
+//	None
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+	switch {
+	case errors.Is(err, rule.ErrInvalidArgument):
+		os.Exit(exitInvalidArgs)
+	case errors.Is(err, rule.ErrLineTooLong):
+		os.Exit(exitLineTooLong)
+	case errors.Is(err, rule.ErrRead):
+		os.Exit(exitRead)
+	case errors.Is(err, rule.ErrWrite):
+		os.Exit(exitWrite)
 	}
+	os.Exit(exitError)
 }
 
 // printUsage prints usage information for the program
 func printUsage() {
-	fmt.Println(fmt.Sprintf("\nModes for rulecat (version %s):", version))
-	fmt.Println("\n  append\tCreates append rules from text")
-	fmt.Println("\t\tExample: stdin | rulecat append")
-	fmt.Println("\t\tExample: stdin | rulecat append remove")
-	fmt.Println("\t\tExample: stdin | rulecat append shift")
-	fmt.Println("\n  prepend\tCreates prepend rules from text")
-	fmt.Println("\t\tExample: stdin | rulecat prepend")
-	fmt.Println("\t\tExample: stdin | rulecat prepend remove")
-	fmt.Println("\t\tExample: stdin | rulecat prepend shift")
-	fmt.Println("\n  blank\t\tCreates blank lines from text")
-	fmt.Println("\t\tExample: stdin | rulecat blank")
-	fmt.Println("\n  [RULE-FILE]\tCreate cartesian product of a file and text")
-	fmt.Println("\t\tExample: stdin | rulecat [FILE]")
-	fmt.Println("\n  chars\t\tCreates custom rules per character from text")
-	fmt.Println("\t\tExample: stdin | rulecat chars [RULE]")
-	fmt.Println("\n  insert\tCreates insert rules from from text")
-	fmt.Println("\t\tExample: stdin | rulecat insert [START-INDEX]")
-	fmt.Println("\n  overwrite\tCreates overwrite rules from from text")
-	fmt.Println("\t\tExample: stdin | rulecat overwrite [START-INDEX]")
-	fmt.Println("\n  toggle\tCreates toggle rules from from text")
-	fmt.Println("\t\tExample: stdin | rulecat toggle [START-INDEX]")
-	fmt.Println("\n  encode\tURL, HTML, and Unicode escape encodes input and prints new output")
-	fmt.Println("\t\tExample: stdin | rulecat encode")
-	fmt.Println("\n  combo\t\tCombines multiple modes into one rule per line (toggle, prepend, append, insert)")
-	fmt.Println("\t\tExample: stdin | rulecat combo [MODE-A] [MODE-B]")
+	fmt.Printf("\nModes for rulecat (version %s):\n", version)
+	for _, cmd := range commands {
+		fmt.Printf("\n  %s%s%s\n", cmd.name, padding(cmd.name), cmd.summary)
+		for _, example := range cmd.examples {
+			fmt.Printf("\t\tExample: %s\n", example)
+		}
+	}
+	fmt.Println("\nShared flags:")
+	fmt.Println("\n  --input\tRead input from files, directories, glob patterns, or - for stdin")
+	fmt.Println("\t\tinstead of stdin and decompress .gz, .bz2, .xz, and .zst files")
+	fmt.Println("\t\tExample: rulecat append --input words.txt --input 'lists/*.gz'")
+	fmt.Println("\n  --output\tWrite output to a file instead of stdout")
+	fmt.Println("\n  --format\tWrites append, prepend, insert, overwrite, toggle, leet, cartesian,")
+	fmt.Println("\t\tencode --rules, and learn-subs --rules rules in Hashcat or John the")
+	fmt.Println("\t\tRipper syntax and selects the checks of validate (hashcat, john)")
+	fmt.Println("\t\tOther modes do not accept it")
+	fmt.Println("\t\tExample: stdin | rulecat append --format john")
+	fmt.Println("\n  --unique\tRemove repeated output lines keeping the first seen")
+	fmt.Println("\t\tExample: stdin | rulecat append --unique")
+	fmt.Println("\n  --unique-fp\tRemove repeated output lines with a Bloom filter of fixed memory")
+	fmt.Println("\t\tsized by --unique-size and this false positive rate")
+	fmt.Println("\t\tExample: stdin | rulecat append --unique-fp 0.001 --unique-size 500000000")
+	fmt.Println("\nUse rulecat [MODE] --help for the flags of a mode and rulecat --version")
+	fmt.Println("for the version.")
+}
+
+// printCommandUsage prints usage information and flags for a mode
+//
+// Args:
+//
+//	cmd (command): Mode to print
+//	fs (*flag.FlagSet): Flags of the mode
+//
+// Returns:
+//
+//	None
+func printCommandUsage(cmd command, fs *flag.FlagSet) {
+	fmt.Printf("\nUsage: rulecat %s [flags]\n\n  %s\n", cmd.name, cmd.summary)
+	for _, example := range cmd.examples {
+		fmt.Printf("\tExample: %s\n", example)
+	}
+	fmt.Println("\nFlags:")
+	fs.SetOutput(os.Stdout)
+	fs.PrintDefaults()
+	if len(cmd.notes) > 0 {
+		fmt.Printf("\n%s\n", strings.Join(cmd.notes, "\n"))
+	}
+}
+
+// padding aligns mode summaries to the second tab stop
+func padding(name string) string {
+	if len(name) < 6 {
+		return "\t\t"
+	}
+	return "\t"
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jakewnuk/rulecat/pkg/reform"
+	"github.com/jakewnuk/rulecat/pkg/rule"
+)
+
+// runCommand runs a mode with arguments and returns what it wrote
+func runCommand(t *testing.T, name string, args ...string) (string, error) {
+	t.Helper()
+	cmd, ok := findCommand(name)
+	if !ok {
+		t.Fatalf("findCommand(%q) found no mode", name)
+	}
+	opts := &options{}
+	fs := newFlagSet(cmd, opts)
+	run := cmd.setup(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return "", err
+	}
+	opts.output = filepath.Join(t.TempDir(), "out")
+	env, err := opts.environment()
+	if err != nil {
+		return "", err
+	}
+	err = run(env, positional)
+	for _, f := range env.flushers {
+		f.Flush()
+	}
+	env.close()
+	out, readErr := os.ReadFile(opts.output)
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	return string(out), err
+}
+
+func TestValidateFiles(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.rule"), filepath.Join(dir, "b.rule")
+	if err := os.WriteFile(a, []byte("u\nTz\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("X123\n$1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := runCommand(t, "validate", a, b)
+	want := "line 2: column 2: 'T': invalid position 'z', must be 0-9 or A-Z\nline 3: column 1: 'X': memory function is not supported on GPU\n"
+	if got != want || err == nil {
+		t.Errorf("validate %s %s = %q, %v; want %q and an error", a, b, got, err, want)
+	}
+
+	got, err = runCommand(t, "validate", "--clean", b, a)
+	if want := "$1\nu\n"; got != want || err != nil {
+		t.Errorf("validate --clean %s %s = %q, %v; want %q", b, a, got, err, want)
+	}
+}
+
+func TestFormatFlag(t *testing.T) {
+	if _, err := runCommand(t, "chars", "--format", "john"); err == nil {
+		t.Errorf("chars --format john error = nil; want an error")
+	}
+	dir := t.TempDir()
+	words := filepath.Join(dir, "words.txt")
+	if err := os.WriteFile(words, []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := runCommand(t, "append", "--format", "john", "--input", words)
+	if want := "[List.Rules:rulecat]\n$a\n"; got != want || err != nil {
+		t.Errorf("append --format john = %q, %v; want %q", got, err, want)
+	}
+	if _, err := runCommand(t, "append", "--format", "bogus", "--input", words); !errors.Is(err, rule.ErrInvalidArgument) {
+		t.Errorf("append --format bogus error = %v; want ErrInvalidArgument", err)
+	}
+}
+
+func TestCodecNotes(t *testing.T) {
+	notes := codecNotes("(default)", func(c reform.Codec) bool { return c.Name == "url" })
+	if len(notes) != len(reform.Codecs())+1 {
+		t.Fatalf("codecNotes() = %d lines; want a heading and %d codecs", len(notes), len(reform.Codecs()))
+	}
+	for i, c := range reform.Codecs() {
+		if c.Description == "" || !strings.Contains(notes[i+1], c.Name) || !strings.Contains(notes[i+1], c.Description) {
+			t.Errorf("codecNotes() line %q; want %s and its description", notes[i+1], c.Name)
+		}
+	}
+	if want := "  url           URL query encoding (default)"; notes[1] != want {
+		t.Errorf("codecNotes() line = %q; want %q", notes[1], want)
+	}
+}
@@ -0,0 +1,103 @@
+// Package dedupe contains the logic for removing rules that behave the same
+package dedupe
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jakewnuk/rulecat/pkg/engine"
+	"github.com/jakewnuk/rulecat/pkg/optimize"
+)
+
+// rejected marks a probe word that a rule rejected in a fingerprint
+const rejected = "\x00"
+
+// Key returns the value used to group equivalent rules
+//
+// # Rules that cannot be parsed are grouped by their exact text
+//
+// Args:
+//
+//	rule (string): Rule line
+//
+// Returns:
+//
+//	(string): Normalized rule or the original text
+func Key(rule string) string {
+	normalized, err := optimize.Normalize(rule)
+	if err != nil {
+		return rejected + rule
+	}
+	return normalized
+}
+
+// Fingerprint applies a rule to each probe word and joins the results
+//
+// Args:
+//
+//	rule (string): Rule line
+//	probes ([]string): Words to apply the rule to
+//
+// Returns:
+//
+//	(string): Joined outputs where rejected words are marked
+func Fingerprint(rule string, probes []string) string {
+	r, err := engine.Compile(rule)
+	if err != nil {
+		return rejected + rule
+	}
+
+	var b strings.Builder
+	for _, probe := range probes {
+		candidate, err := r.Apply(probe)
+		if err != nil {
+			candidate = rejected
+		}
+		b.WriteString(candidate)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// DedupeRules prints one rule per equivalence class in first seen order
+//
+// # When probes are given rules with the same normalized form must also
+// produce the same output for every probe word to be considered duplicates
+//
+// Args:
+//
+//	stdIn (*bufio.Scanner): Rule lines as a buffer
+//	w (io.Writer): Destination for the output
+//	probes ([]string): Words used to confirm equivalence or nil
+//
+// Returns:
+//
+//	None
+func DedupeRules(stdIn *bufio.Scanner, w io.Writer, probes []string) {
+	seen := make(map[string][]string)
+	for stdIn.Scan() {
+		line := stdIn.Text()
+		key := Key(line)
+
+		fingerprint := ""
+		if probes != nil {
+			fingerprint = Fingerprint(line, probes)
+		}
+
+		duplicate := false
+		for _, existing := range seen[key] {
+			if existing == fingerprint {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+
+		seen[key] = append(seen[key], fingerprint)
+		fmt.Fprintln(w, line)
+	}
+}
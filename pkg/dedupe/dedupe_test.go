@@ -0,0 +1,80 @@
+package dedupe
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jakewnuk/rulecat/pkg/engine"
+)
+
+// probes are short and long words so length dependent rules are told apart
+var probes = []string{"", "a", "ab", "abcd", "PassWord123"}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		same bool
+	}{
+		{"$1 $2", "$1$2:", true},
+		{"T0 T0 $a", "$a", true},
+		{"r r l l", "l", true},
+		{"$a ]", ":", false},
+		{"[ [", "O02", false},
+		{"D3 D3", "O32", false},
+		{"O12 D1", "O13", false},
+		{"O02 [", "O03", false},
+		{"$1", "$2", false},
+		{"bogus(", "bogus(", true},
+	}
+
+	for _, test := range tests {
+		if got := Key(test.a) == Key(test.b); got != test.same {
+			t.Errorf("Key(%q) == Key(%q) is %v; want %v", test.a, test.b, got, test.same)
+		}
+		if !test.same {
+			continue
+		}
+		// rules with the same key must give the same output on every probe
+		if _, err := engine.Compile(test.a); err == nil && Fingerprint(test.a, probes) != Fingerprint(test.b, probes) {
+			t.Errorf("Key(%q) == Key(%q) but the rules differ on %q", test.a, test.b, probes)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"[ [", "\n\n\ncd\nssWord123\n"},
+		{"O02", "\na\n\ncd\nssWord123\n"},
+		{"<2", "\na\nab\n\x00\n\x00\n"},
+		{"bogus(", "\x00bogus("},
+	}
+
+	for _, test := range tests {
+		if got := Fingerprint(test.rule, probes); got != test.want {
+			t.Errorf("Fingerprint(%q) = %q; want %q", test.rule, got, test.want)
+		}
+	}
+}
+
+func TestDedupeRules(t *testing.T) {
+	rules := "$1 $2\n$1 $2 :\n[ [\nO02\nT0 T0 $a\n$a\n"
+	for _, test := range []struct {
+		probes []string
+		want   string
+	}{
+		{nil, "$1 $2\n[ [\nO02\nT0 T0 $a\n"},
+		{probes, "$1 $2\n[ [\nO02\nT0 T0 $a\n"},
+	} {
+		var out bytes.Buffer
+		DedupeRules(bufio.NewScanner(strings.NewReader(rules)), &out, test.probes)
+		if out.String() != test.want {
+			t.Errorf("DedupeRules() wrote %q; want %q", out.String(), test.want)
+		}
+	}
+}
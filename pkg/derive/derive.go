@@ -0,0 +1,371 @@
+// Package derive contains the logic for finding the rule that transforms a
+// base word into a password
+package derive
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jakewnuk/rulecat/pkg/engine"
+	"github.com/jakewnuk/rulecat/pkg/parser"
+	"github.com/jakewnuk/rulecat/pkg/utils"
+	"github.com/jakewnuk/rulecat/pkg/validate"
+)
+
+// StepKind is the kind of edit in an alignment
+type StepKind int
+
+// Edit kinds produced by Align
+const (
+	Match StepKind = iota
+	Substitute
+	Insert
+	Delete
+)
+
+// Step is a single edit in an alignment of two words
+type Step struct {
+	Kind StepKind
+	// From is the base character for Match, Substitute and Delete
+	From byte
+	// To is the target character for Match, Substitute and Insert
+	To byte
+}
+
+// ErrNoRule is returned when no rule can express the transformation
+var ErrNoRule = errors.New("no rule found")
+
+// wordFunctions are the whole word functions tried before editing
+var wordFunctions = []byte{0, 'l', 'u', 'c', 'C', 't', 'd', 'r'}
+
+// Align finds the minimal edit alignment of base onto target
+//
+// # Ties are broken in favor of insertions at the end of the word
+//
+// Args:
+//
+//	base (string): Starting word
+//	target (string): Word to reach
+//
+// Returns:
+//
+//	([]Step): Edits in order from the start of the word
+func Align(base string, target string) []Step {
+	n, m := len(base), len(target)
+	dp := make([][]int, n+1)
+	for i := range dp {
+		dp[i] = make([]int, m+1)
+		dp[i][0] = i
+	}
+	for j := 0; j <= m; j++ {
+		dp[0][j] = j
+	}
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			cost := 1
+			if base[i-1] == target[j-1] {
+				cost = 0
+			}
+			dp[i][j] = min(dp[i-1][j-1]+cost, dp[i-1][j]+1, dp[i][j-1]+1)
+		}
+	}
+
+	var steps []Step
+	i, j := n, m
+	for i > 0 || j > 0 {
+		switch {
+		case j > 0 && dp[i][j] == dp[i][j-1]+1:
+			steps = append(steps, Step{Kind: Insert, To: target[j-1]})
+			j--
+		case i > 0 && j > 0 && base[i-1] == target[j-1] && dp[i][j] == dp[i-1][j-1]:
+			steps = append(steps, Step{Kind: Match, From: base[i-1], To: target[j-1]})
+			i, j = i-1, j-1
+		case i > 0 && j > 0 && dp[i][j] == dp[i-1][j-1]+1:
+			steps = append(steps, Step{Kind: Substitute, From: base[i-1], To: target[j-1]})
+			i, j = i-1, j-1
+		default:
+			steps = append(steps, Step{Kind: Delete, From: base[i-1]})
+			i--
+		}
+	}
+
+	for l, r := 0, len(steps)-1; l < r; l, r = l+1, r-1 {
+		steps[l], steps[r] = steps[r], steps[l]
+	}
+	return steps
+}
+
+// Derive finds a short rule that transforms base into target
+//
+// Args:
+//
+//	base (string): Starting word
+//	target (string): Word to reach
+//
+// Returns:
+//
+//	(string): Rule line
+//	(error): ErrNoRule if the transformation cannot be expressed
+func Derive(base string, target string) (string, error) {
+	var best []parser.Operation
+	found := false
+	for _, c := range wordFunctions {
+		ops, err := deriveWith(c, base, target)
+		if err != nil {
+			continue
+		}
+		rule := parser.Format(ops)
+		if got, err := engine.Apply(base, rule); err != nil || got != target {
+			continue
+		}
+		if !found || len(ops) < len(best) || (len(ops) == len(best) && len(rule) < len(parser.Format(best))) {
+			best = ops
+			found = true
+		}
+	}
+
+	if !found {
+		return "", ErrNoRule
+	}
+	if len(best) == 0 {
+		return ":", nil
+	}
+	return parser.Format(best), nil
+}
+
+// deriveWith builds a rule starting with a whole word function followed by
+// substitutions and positional edits
+//
+// Args:
+//
+//	wordOp (byte): Whole word function to start with or zero for none
+//	base (string): Starting word
+//	target (string): Word to reach
+//
+// Returns:
+//
+//	([]parser.Operation): Operations in order
+//	(error): ErrNoRule if a position is out of range
+func deriveWith(wordOp byte, base string, target string) ([]parser.Operation, error) {
+	var ops []parser.Operation
+	word := base
+	if wordOp != 0 {
+		ops = append(ops, parser.Operation{Opcode: wordOp})
+		word, _ = engine.Apply(word, string(wordOp))
+	}
+
+	subs := substitutions(Align(word, target))
+	if len(subs) > 0 {
+		ops = append(ops, subs...)
+		word, _ = engine.Apply(word, parser.Format(subs))
+	}
+
+	steps := Align(word, target)
+	first, last := 0, len(steps)
+	for first < last && steps[first].Kind == Insert {
+		first++
+	}
+	for last > first && steps[last-1].Kind == Insert {
+		last--
+	}
+
+	pos := 0
+	for k := first; k < last; k++ {
+		s := steps[k]
+		if s.Kind != Match && pos > parser.MaxPosition {
+			return nil, ErrNoRule
+		}
+		switch s.Kind {
+		case Match:
+			pos++
+		case Substitute:
+			if lowerASCII(s.From) == lowerASCII(s.To) {
+				ops = append(ops, parser.Operation{Opcode: 'T', Positions: []int{pos}})
+			} else {
+				ops = append(ops, parser.Operation{Opcode: 'o', Positions: []int{pos}, Chars: []byte{s.To}})
+			}
+			pos++
+		case Insert:
+			ops = append(ops, parser.Operation{Opcode: 'i', Positions: []int{pos}, Chars: []byte{s.To}})
+			pos++
+		case Delete:
+			switch {
+			case pos == 0:
+				ops = append(ops, parser.Operation{Opcode: '['})
+			case onlyDeletes(steps[k:last]):
+				ops = append(ops, parser.Operation{Opcode: ']'})
+			default:
+				ops = append(ops, parser.Operation{Opcode: 'D', Positions: []int{pos}})
+			}
+		}
+	}
+
+	// prepends are applied last byte first so multibyte characters keep
+	// their byte order
+	for i := first - 1; i >= 0; i-- {
+		ops = append(ops, parser.Operation{Opcode: '^', Chars: []byte{target[i]}})
+	}
+	for _, c := range []byte(target[len(target)-(len(steps)-last):]) {
+		ops = append(ops, parser.Operation{Opcode: '$', Chars: []byte{c}})
+	}
+	return ops, nil
+}
+
+// substitutions finds characters that are replaced the same way everywhere
+// in an alignment so they can be written as s rules
+//
+// Args:
+//
+//	steps ([]Step): Alignment of the current word and the target
+//
+// Returns:
+//
+//	([]parser.Operation): Substitution operations sorted by character
+func substitutions(steps []Step) []parser.Operation {
+	mapping := make(map[byte]byte)
+	counts := make(map[byte]int)
+	invalid := make(map[byte]bool)
+	for _, s := range steps {
+		switch s.Kind {
+		case Match, Substitute:
+			if to, ok := mapping[s.From]; ok && to != s.To {
+				invalid[s.From] = true
+			}
+			mapping[s.From] = s.To
+			counts[s.From]++
+		case Delete:
+			invalid[s.From] = true
+		}
+	}
+
+	var froms []byte
+	for from, to := range mapping {
+		if invalid[from] || from == to {
+			continue
+		}
+		// case changes of a single character are shorter as toggles
+		if lowerASCII(from) == lowerASCII(to) && counts[from] < 2 {
+			continue
+		}
+		froms = append(froms, from)
+	}
+	sort.Slice(froms, func(i, j int) bool { return froms[i] < froms[j] })
+
+	// skip chains such as a->b b->c that would change earlier replacements
+	var ops []parser.Operation
+	for _, from := range froms {
+		to := mapping[from]
+		if _, chained := mapping[to]; chained && !invalid[to] && mapping[to] != to {
+			continue
+		}
+		ops = append(ops, parser.Operation{Opcode: 's', Chars: []byte{from, to}})
+	}
+	return ops
+}
+
+// onlyDeletes checks if every step is a deletion
+func onlyDeletes(steps []Step) bool {
+	for _, s := range steps {
+		if s.Kind != Delete {
+			return false
+		}
+	}
+	return true
+}
+
+// lowerASCII lowercases an ASCII letter
+func lowerASCII(b byte) byte {
+	if b >= 'A' && b <= 'Z' {
+		return b + 32
+	}
+	return b
+}
+
+// DeriveRules prints the rule that transforms each base word into its password
+//
+// # When plains is nil each line of bases is read as a base:password pair
+//
+// Args:
+//
+//	bases (*bufio.Scanner): Base words or base:password pairs as a buffer
+//	plains (*bufio.Scanner): Passwords matching each base word line or nil
+//	w (io.Writer): Destination for the output
+//
+// Returns:
+//
+//	None
+func DeriveRules(bases *bufio.Scanner, plains *bufio.Scanner, w io.Writer) {
+	for bases.Scan() {
+		base, target := bases.Text(), ""
+		if plains == nil {
+			var ok bool
+			if base, target, ok = splitPair(base); !ok {
+				continue
+			}
+		} else {
+			if !plains.Scan() {
+				return
+			}
+			target = plains.Text()
+		}
+
+		rule, err := Derive(base, target)
+		if err == nil {
+			fmt.Fprintln(w, rule)
+		}
+	}
+}
+
+// EncodingRules creates the rules that transform a string into each of its
+// encodings
+//
+// # Rules use s substitutions where a character is encoded the same way
+// everywhere and positional functions otherwise so positional rules only
+// match words with the same layout. Encodings that cannot be written within
+// Hashcat limits are skipped
+//
+// Args:
+//
+//	s (string): Input string
+//	encoded ([]string): Encodings of the input string
+//
+// Returns:
+//
+//	([]string): Rules in the order of the encodings
+func EncodingRules(s string, encoded []string) []string {
+	var rules []string
+	for _, e := range encoded {
+		rule, err := Derive(s, e)
+		if err != nil || len(validate.CheckRule(rule)) > 0 {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// splitPair splits a base:password line and decodes each half in the
+// $HEX[...] format
+//
+// Args:
+//
+//	line (string): Line with a base word and password
+//
+// Returns:
+//
+//	(string): Base word
+//	(string): Password
+//	(bool): If the line has a separator
+func splitPair(line string) (string, string, bool) {
+	base, target, ok := strings.Cut(line, ":")
+	if !ok {
+		return "", "", false
+	}
+	base, _ = utils.DecodeHexString(base)
+	target, _ = utils.DecodeHexString(target)
+	return base, target, true
+}
@@ -0,0 +1,207 @@
+package derive
+
+import (
+	"bufio"
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jakewnuk/rulecat/pkg/dialect"
+	"github.com/jakewnuk/rulecat/pkg/engine"
+)
+
+func TestDerive(t *testing.T) {
+	tests := []struct {
+		base   string
+		target string
+		want   string
+	}{
+		{"password", "password", ":"},
+		{"password", "P@ssw0rd!", "c sa@ so0 $!"},
+		{"password", "Password1", "c $1"},
+		{"pass", "123pass", "^3 ^2 ^1"},
+		{"pass", "épass", "^\\xA9 ^\\xC3"},
+		{"monkey", "m0nk3y", "se3 so0"},
+		{"dragon", "dragn", "D4"},
+		{"test", "tset", "r"},
+		{"tester", "tster", "D1"},
+		{"dragons", "dragon", "]"},
+		{"xdragon", "dragon", "["},
+		{"letmein", "letmeinletmein", "d"},
+	}
+
+	for _, test := range tests {
+		got, err := Derive(test.base, test.target)
+		if err != nil {
+			t.Errorf("Derive(%q, %q) returned error %v", test.base, test.target, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Derive(%q, %q) = %q; want %q", test.base, test.target, got, test.want)
+		}
+	}
+}
+
+func TestDeriveApplies(t *testing.T) {
+	tests := []struct {
+		base   string
+		target string
+	}{
+		{"summer", "$uMMer2024"},
+		{"café", "Café!"},
+		{"pass", "épass"},
+		{"abcabc", "xbcxbcd"},
+		{"hello", ""},
+		{"", "hello"},
+	}
+
+	for _, test := range tests {
+		rule, err := Derive(test.base, test.target)
+		if err != nil {
+			t.Errorf("Derive(%q, %q) returned error %v", test.base, test.target, err)
+			continue
+		}
+		got, err := engine.Apply(test.base, rule)
+		if err != nil || got != test.target {
+			t.Errorf("Apply(%q, %q) = %q; want %q", test.base, rule, got, test.target)
+		}
+	}
+}
+
+func TestDeriveRules(t *testing.T) {
+	pairs := "pass:pass1\nnocolon\n$HEX[70613a73]:$HEX[70613a730a]\npass:$HEX[7040737331]\n"
+	var out bytes.Buffer
+	DeriveRules(bufio.NewScanner(strings.NewReader(pairs)), nil, &out)
+	if want := "$1\n$\\x0A\nsa@ $1\n"; out.String() != want {
+		t.Errorf("DeriveRules() wrote %q; want %q", out.String(), want)
+	}
+}
+
+func TestEncodingRules(t *testing.T) {
+	if got, want := EncodingRules("a b", []string{"a+b", strings.Repeat("%61", 40)}), []string{"s +"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("EncodingRules(%q) = %q; want %q", "a b", got, want)
+	}
+
+	for word, encoded := range map[string][]string{
+		"p@ss":     {"p%40ss", "cEBzcw=="},
+		"<a>&b":    {"%3Ca%3E%26b", "&lt;a&gt;&amp;b"},
+		"pässword": {"p%C3%A4ssword", "p\\u00e4ssword"},
+	} {
+		rules := EncodingRules(word, encoded)
+		if len(rules) != len(encoded) {
+			t.Fatalf("EncodingRules(%q) = %q; want a rule for each of %q", word, rules, encoded)
+		}
+		for i, rule := range rules {
+			got, err := engine.Apply(word, rule)
+			if err != nil || got != encoded[i] {
+				t.Errorf("Apply(%q, %q) = %q, %v; want %q", word, rule, got, err, encoded[i])
+			}
+		}
+	}
+}
+
+func TestLearnSubstitutions(t *testing.T) {
+	pairs := "password:P@ssw0rd\nmonkey:m0nk3y\nsecret:$3cr3t\nnocolon\nhello:HELLO\n"
+	subs := LearnSubstitutions(bufio.NewScanner(strings.NewReader(pairs)), nil).Substitutions(1)
+	want := []Substitution{{'e', '3', 3}, {'o', '0', 2}, {'a', '@', 1}, {'s', '$', 1}}
+	if !reflect.DeepEqual(subs, want) {
+		t.Errorf("Substitutions() = %v; want %v", subs, want)
+	}
+
+	bases := bufio.NewScanner(strings.NewReader("dragon\nalpha\n"))
+	plains := bufio.NewScanner(strings.NewReader("dr@g0n\n@lph@\n"))
+	subs = LearnSubstitutions(bases, plains).Substitutions(2)
+	if want := []Substitution{{'a', '@', 3}}; !reflect.DeepEqual(subs, want) {
+		t.Errorf("Substitutions(2) = %v; want %v", subs, want)
+	}
+
+	var out bytes.Buffer
+	WriteSubstitutions(&out, []Substitution{{'a', '@', 3}, {'\t', 'x', 1}}, false, dialect.Hashcat{})
+	WriteSubstitutions(&out, []Substitution{{'a', '@', 3}}, true, dialect.John{Section: "test"})
+	if want := "3\ta\t@\n1\t\\x09\tx\n[List.Rules:test]\nsa@\n"; out.String() != want {
+		t.Errorf("WriteSubstitutions() wrote %q; want %q", out.String(), want)
+	}
+}
+
+func TestDictionary(t *testing.T) {
+	dict := NewDictionary([]string{"password", "passport", "summer", "shadow"})
+	tests := []struct {
+		target string
+		want   string
+		ok     bool
+	}{
+		{"p@ssw0rd", "password", true},
+		{"p@ssw0rd1", "password", true},
+		{"5ummer2024", "summer", true},
+		{"5ummer20245", "", false},
+		{"5ummer!", "summer", true},
+		{"shad0", "shadow", true},
+		{"Pa55port", "passport", true},
+		{"5umm3r", "summer", true},
+		{"PASSWORD", "password", false},
+		{"xxxxxx", "", false},
+		{"short", "", false},
+	}
+
+	for _, test := range tests {
+		got, ok := dict.Match(test.target)
+		if got != test.want || ok != test.ok {
+			t.Errorf("Match(%q) = %q, %v; want %q, %v", test.target, got, ok, test.want, test.ok)
+		}
+	}
+
+	plains := bufio.NewScanner(strings.NewReader("p@ssw0rd\nsh@d0w\n5umm3r\n"))
+	subs := LearnFromDictionary(plains, dict).Substitutions(1)
+	want := []Substitution{{'a', '@', 2}, {'o', '0', 2}, {'s', '5', 1}, {'e', '3', 1}}
+	if !reflect.DeepEqual(subs, want) {
+		t.Errorf("Substitutions() = %v; want %v", subs, want)
+	}
+}
+
+func TestBoundedDistance(t *testing.T) {
+	words := []string{"", "a", "ab", "password", "p@ssw0rd1", "drowssap", "pass", "summer2024", "xyz"}
+	for _, a := range words {
+		for _, b := range words {
+			want := 0
+			for _, s := range Align(a, b) {
+				if s.Kind != Match {
+					want++
+				}
+			}
+			for limit := 0; limit <= 10; limit++ {
+				rows := make([]int, 2*(len(b)+1))
+				got := boundedDistance(a, b, limit, rows[:len(b)+1], rows[len(b)+1:])
+				if exp := min(want, limit+1); got != exp {
+					t.Errorf("boundedDistance(%q, %q, %d) = %d; want %d", a, b, limit, got, exp)
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkDictionaryMatch matches passwords against a wordlist of the size
+// of a common English dictionary
+func BenchmarkDictionaryMatch(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	words := make([]string, 100_000)
+	for i := range words {
+		word := make([]byte, 4+r.Intn(9))
+		for j := range word {
+			word[j] = byte('a' + r.Intn(26))
+		}
+		words[i] = string(word)
+	}
+	words = append(words, "password", "summer", "dragon", "monkey")
+	dict := NewDictionary(words)
+	plains := []string{"p@ssw0rd1", "Summer2024!", "dr@g0n", "m0nk3y", "zzqxv"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dict.Match(plains[i%len(plains)])
+	}
+	if _, ok := dict.Match("p@ssw0rd1"); !ok {
+		b.Fatalf("Match(%q) found no word", "p@ssw0rd1")
+	}
+}
@@ -0,0 +1,371 @@
+package derive
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jakewnuk/rulecat/pkg/dialect"
+	"github.com/jakewnuk/rulecat/pkg/parser"
+)
+
+// Substitution is a character replacement seen between base words and
+// passwords
+type Substitution struct {
+	// From is the character in the base word
+	From byte
+	// To is the character in the password
+	To byte
+	// Count is the number of times the replacement was seen
+	Count int
+}
+
+// Rule returns the substitution as an sXY rule
+func (s Substitution) Rule() string {
+	return parser.Format([]parser.Operation{{Opcode: 's', Chars: []byte{s.From, s.To}}})
+}
+
+// Learner counts the substitutions in aligned base words and passwords
+//
+// # Case changes are ignored as they are found by toggles
+type Learner struct {
+	counts map[[2]byte]int
+	// order is the first seen order of the substitutions
+	order [][2]byte
+}
+
+// NewLearner creates an empty Learner
+func NewLearner() *Learner {
+	return &Learner{counts: make(map[[2]byte]int)}
+}
+
+// Add aligns a base word with its password and counts the substitutions
+//
+// Args:
+//
+//	base (string): Starting word
+//	target (string): Password made from the base word
+//
+// Returns:
+//
+//	None
+func (l *Learner) Add(base string, target string) {
+	for _, s := range Align(base, target) {
+		if s.Kind != Substitute || lowerASCII(s.From) == lowerASCII(s.To) {
+			continue
+		}
+		key := [2]byte{s.From, s.To}
+		if l.counts[key] == 0 {
+			l.order = append(l.order, key)
+		}
+		l.counts[key]++
+	}
+}
+
+// Substitutions returns every substitution seen so far
+//
+// Args:
+//
+//	minCount (int): Minimum count to include a substitution
+//
+// Returns:
+//
+//	([]Substitution): Substitutions sorted by count with ties in first seen
+//	order
+func (l *Learner) Substitutions(minCount int) []Substitution {
+	var subs []Substitution
+	for _, key := range l.order {
+		if count := l.counts[key]; count >= minCount {
+			subs = append(subs, Substitution{From: key[0], To: key[1], Count: count})
+		}
+	}
+	sort.SliceStable(subs, func(i, j int) bool { return subs[i].Count > subs[j].Count })
+	return subs
+}
+
+// Dictionary finds the base word of a password among known words
+//
+// # A Dictionary is not safe for concurrent use
+type Dictionary struct {
+	words []string
+	// folded are the words with ASCII letters in lower case
+	folded []string
+	// grams holds the words that contain each two character sequence
+	grams map[uint16][]gramCount
+	// shared counts the sequences each word shares with a password
+	shared []int
+	// touched are the words with a shared count to reset
+	touched []int
+}
+
+// gramCount is the number of times a word contains a two character sequence
+type gramCount struct {
+	word int
+	n    int
+}
+
+// NewDictionary creates a Dictionary from words
+//
+// Args:
+//
+//	words ([]string): Known base words
+//
+// Returns:
+//
+//	(*Dictionary): Dictionary of the words
+func NewDictionary(words []string) *Dictionary {
+	d := &Dictionary{
+		words:  words,
+		folded: make([]string, len(words)),
+		grams:  make(map[uint16][]gramCount),
+		shared: make([]int, len(words)),
+	}
+	for i, word := range words {
+		d.folded[i] = foldASCII(word)
+		for g, n := range bigrams(d.folded[i]) {
+			d.grams[g] = append(d.grams[g], gramCount{word: i, n: n})
+		}
+	}
+	return d
+}
+
+// Match finds the word that needs the fewest edits ignoring case to become
+// a password
+//
+// # Only words that share a two character sequence with the password are
+// compared. Words that need more edits than half of the characters of the
+// password are not matched and ties are broken by dictionary order
+//
+// Args:
+//
+//	target (string): Password to find the base word of
+//
+// Returns:
+//
+//	(string): Closest word
+//	(bool): If a word differs by at least one character and was matched
+func (d *Dictionary) Match(target string) (string, bool) {
+	folded := foldASCII(target)
+	maxDiff := len(target) / 2
+
+	// each edit removes at most two shared sequences so words are grouped
+	// by the fewest edits they can need
+	for g, n := range bigrams(folded) {
+		for _, c := range d.grams[g] {
+			if d.shared[c.word] == 0 {
+				d.touched = append(d.touched, c.word)
+			}
+			d.shared[c.word] += min(n, c.n)
+		}
+	}
+	buckets := make([][]int, maxDiff+1)
+	for _, i := range d.touched {
+		longest := max(len(d.folded[i]), len(folded))
+		bound := max(abs(len(d.folded[i])-len(folded)), (longest-d.shared[i])/2)
+		if bound <= maxDiff {
+			buckets[bound] = append(buckets[bound], i)
+		}
+		d.shared[i] = 0
+	}
+	d.touched = d.touched[:0]
+
+	rows := make([]int, 2*(len(folded)+1))
+	best, bestDiff := -1, maxDiff
+	for bound := 0; bound <= bestDiff; bound++ {
+		sort.Ints(buckets[bound])
+		for _, i := range buckets[bound] {
+			if best >= 0 && bound == bestDiff && i > best {
+				break
+			}
+			diff := boundedDistance(d.folded[i], folded, bestDiff, rows[:len(folded)+1], rows[len(folded)+1:])
+			if diff == 0 {
+				return d.words[i], false
+			}
+			if diff > bestDiff {
+				continue
+			}
+			if best < 0 || diff < bestDiff || i < best {
+				best, bestDiff = i, diff
+			}
+		}
+	}
+	if best < 0 {
+		return "", false
+	}
+	return d.words[best], true
+}
+
+// bigrams counts the two character sequences of a string
+func bigrams(s string) map[uint16]int {
+	grams := make(map[uint16]int)
+	for i := 1; i < len(s); i++ {
+		grams[uint16(s[i-1])<<8|uint16(s[i])]++
+	}
+	return grams
+}
+
+// boundedDistance finds the edit distance of two strings if it is at most a
+// limit
+//
+// # Only cells within limit of the diagonal are computed and the search
+// stops once a whole row is over the limit
+//
+// Args:
+//
+//	a (string): First string
+//	b (string): Second string
+//	limit (int): Largest distance of interest
+//	prev ([]int): Scratch row of len(b)+1 values
+//	cur ([]int): Scratch row of len(b)+1 values
+//
+// Returns:
+//
+//	(int): Edit distance or limit+1 if it is over the limit
+func boundedDistance(a string, b string, limit int, prev []int, cur []int) int {
+	over := limit + 1
+	if abs(len(a)-len(b)) > limit {
+		return over
+	}
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		lo, hi := max(1, i-limit), min(len(b), i+limit)
+		cur[lo-1] = over
+		if lo == 1 {
+			cur[0] = i
+		}
+		rowMin := cur[lo-1]
+		for j := lo; j <= hi; j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j-1]+cost, prev[j]+1, cur[j-1]+1)
+			rowMin = min(rowMin, cur[j])
+		}
+		if hi < len(b) {
+			cur[hi+1] = over
+		}
+		if rowMin > limit {
+			return over
+		}
+		prev, cur = cur, prev
+	}
+	return min(prev[len(b)], over)
+}
+
+// abs returns the absolute value of an int
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// foldASCII lowercases the ASCII letters of a string
+func foldASCII(s string) string {
+	b := []byte(s)
+	for i := range b {
+		b[i] = lowerASCII(b[i])
+	}
+	return string(b)
+}
+
+// LearnSubstitutions counts the substitutions between each base word and
+// its password
+//
+// # When plains is nil each line of bases is read as a base:password pair
+//
+// Args:
+//
+//	bases (*bufio.Scanner): Base words or base:password pairs as a buffer
+//	plains (*bufio.Scanner): Passwords matching each base word line or nil
+//
+// Returns:
+//
+//	(*Learner): Counted substitutions
+func LearnSubstitutions(bases *bufio.Scanner, plains *bufio.Scanner) *Learner {
+	l := NewLearner()
+	for bases.Scan() {
+		base, target := bases.Text(), ""
+		if plains == nil {
+			var ok bool
+			if base, target, ok = splitPair(base); !ok {
+				continue
+			}
+		} else {
+			if !plains.Scan() {
+				break
+			}
+			target = plains.Text()
+		}
+		l.Add(base, target)
+	}
+	return l
+}
+
+// LearnFromDictionary counts the substitutions between each password and
+// its closest word in a dictionary
+//
+// Args:
+//
+//	plains (*bufio.Scanner): Passwords as a buffer
+//	dict (*Dictionary): Known base words
+//
+// Returns:
+//
+//	(*Learner): Counted substitutions
+func LearnFromDictionary(plains *bufio.Scanner, dict *Dictionary) *Learner {
+	l := NewLearner()
+	for plains.Scan() {
+		if base, ok := dict.Match(plains.Text()); ok {
+			l.Add(base, plains.Text())
+		}
+	}
+	return l
+}
+
+// WriteSubstitutions prints substitutions as count<TAB>from<TAB>to lines or
+// as sXY rules
+//
+// # Characters that are not printable are written in \xNN format
+//
+// Args:
+//
+//	w (io.Writer): Destination for the output
+//	subs ([]Substitution): Substitutions in the order to print
+//	rules (bool): Print sXY rules instead of counts
+//	d (dialect.Dialect): Syntax to write rules in
+//
+// Returns:
+//
+//	None
+func WriteSubstitutions(w io.Writer, subs []Substitution, rules bool, d dialect.Dialect) {
+	if !rules {
+		for _, s := range subs {
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Count, charText(s.From), charText(s.To))
+		}
+		return
+	}
+
+	if header := d.Header(); header != "" {
+		fmt.Fprintln(w, header)
+	}
+	for _, s := range subs {
+		output, err := d.Render(s.Rule())
+		if err != nil {
+			continue
+		}
+		fmt.Fprintln(w, output)
+	}
+}
+
+// charText writes a character escaping bytes that are not printable ASCII
+func charText(c byte) string {
+	if c < 0x20 || c > 0x7e {
+		return fmt.Sprintf("\\x%02X", c)
+	}
+	return string(c)
+}
@@ -0,0 +1,550 @@
+// Package dialect contains the logic for writing rules in the syntax of
+// different password crackers
+package dialect
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jakewnuk/rulecat/pkg/parser"
+)
+
+// ErrUnsupported is returned when a function has no equivalent in a dialect
+var ErrUnsupported = errors.New("function has no equivalent")
+
+// Dialect renders Hashcat rules in the syntax of a password cracker
+type Dialect interface {
+	// Name returns the name used to select the dialect
+	Name() string
+	// Header returns the text printed before any rules or an empty string
+	Header() string
+	// Render converts a Hashcat rule line into the dialect
+	Render(rule string) (string, error)
+	// Translate converts a rule line in the dialect into Hashcat syntax
+	Translate(rule string) (string, error)
+}
+
+// New returns the dialect for a format name
+//
+// Args:
+//
+//	name (string): Format name (hashcat or john)
+//
+// Returns:
+//
+//	(Dialect): Dialect for the format
+//	(error): Error if the format is unknown
+func New(name string) (Dialect, error) {
+	switch name {
+	case "", "hashcat":
+		return Hashcat{}, nil
+	case "john":
+		return John{Section: "rulecat"}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q (hashcat, john)", name)
+}
+
+// Hashcat writes rules in Hashcat syntax
+type Hashcat struct{}
+
+// Name returns the name used to select the dialect
+func (Hashcat) Name() string {
+	return "hashcat"
+}
+
+// Header returns an empty string as Hashcat rule files have no header
+func (Hashcat) Header() string {
+	return ""
+}
+
+// Render returns the rule unchanged
+func (Hashcat) Render(rule string) (string, error) {
+	return rule, nil
+}
+
+// Translate checks that the rule can be parsed and returns it unchanged
+func (Hashcat) Translate(rule string) (string, error) {
+	if _, err := parser.Parse(rule); err != nil {
+		return "", err
+	}
+	return rule, nil
+}
+
+// John writes rules in John the Ripper syntax
+type John struct {
+	// Section is the name used in the [List.Rules:Name] header
+	Section string
+}
+
+// johnSame are functions that have the same meaning in John the Ripper
+//
+// Memory functions are left out as John the Ripper initializes memory
+// differently
+var johnSame = map[byte]bool{
+	':': true, 'l': true, 'u': true, 'c': true, 'C': true, 't': true,
+	'T': true, 'r': true, 'd': true, 'f': true, '{': true, '}': true,
+	'D': true, 'x': true, 'O': true, 'i': true, 'o': true, '\'': true,
+	's': true, '@': true, '!': true, '/': true, '(': true, ')': true,
+	'=': true, '%': true,
+}
+
+// johnClasses are functions where ?X is a character class in John the Ripper
+// when it is the first character argument
+var johnClasses = map[byte]bool{
+	's': true, '@': true, '!': true, '/': true, '(': true, ')': true,
+	'=': true, '%': true,
+}
+
+// Name returns the name used to select the dialect
+func (John) Name() string {
+	return "john"
+}
+
+// Header returns the rule section header
+func (j John) Header() string {
+	return fmt.Sprintf("[List.Rules:%s]", j.Section)
+}
+
+// Render converts a Hashcat rule line into John the Ripper syntax
+//
+// # Runs of appends and prepends are written as Az"..." and A0"..."
+//
+// Args:
+//
+//	rule (string): Hashcat rule line
+//
+// Returns:
+//
+//	(string): John the Ripper rule line
+//	(error): Error if the rule is malformed or has no equivalent
+func (John) Render(rule string) (string, error) {
+	ops, err := parser.Parse(rule)
+	if err != nil {
+		return "", err
+	}
+
+	var parts []string
+	for i := 0; i < len(ops); i++ {
+		op := ops[i]
+
+		// group runs of appends and prepends into string commands
+		if op.Opcode == '$' || op.Opcode == '^' {
+			run := []byte{op.Chars[0]}
+			for i+1 < len(ops) && ops[i+1].Opcode == op.Opcode {
+				i++
+				run = append(run, ops[i].Chars[0])
+			}
+			quoted, ok := JohnString(run)
+			if len(run) == 1 || !ok {
+				for _, c := range run {
+					parts = append(parts, string(op.Opcode)+JohnChar(c))
+				}
+				continue
+			}
+			if op.Opcode == '^' {
+				reversed := make([]byte, len(run))
+				for j, c := range run {
+					reversed[len(run)-1-j] = c
+				}
+				quoted, _ = JohnString(reversed)
+				parts = append(parts, "A0"+quoted)
+				continue
+			}
+			parts = append(parts, "Az"+quoted)
+			continue
+		}
+
+		text, err := johnFunction(op)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, text)
+	}
+	if len(parts) == 0 {
+		return ":", nil
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// johnFunction converts a single operation into John the Ripper syntax
+//
+// Args:
+//
+//	op (parser.Operation): Operation to convert
+//
+// Returns:
+//
+//	(string): John the Ripper function
+//	(error): ErrUnsupported if there is no equivalent
+func johnFunction(op parser.Operation) (string, error) {
+	switch {
+	case op.Opcode == '[' || op.Opcode == ']':
+		// [ and ] start preprocessor ranges in John
+		return "\\" + string(op.Opcode), nil
+	case op.Opcode == 'p' && op.Positions[0] == 0:
+		return ":", nil
+	case op.Opcode == 'p' && op.Positions[0] == 1:
+		return "d", nil
+	case op.Opcode == '<' && op.Positions[0] < parser.MaxPosition:
+		// John rejects unless shorter than N where Hashcat allows N
+		return "<" + string(parser.PositionChar(op.Positions[0]+1)), nil
+	case op.Opcode == '>' && op.Positions[0] > 0:
+		// John rejects unless longer than N where Hashcat allows N
+		return ">" + string(parser.PositionChar(op.Positions[0]-1)), nil
+	case johnSame[op.Opcode]:
+		var b strings.Builder
+		b.WriteByte(op.Opcode)
+		p, c := 0, 0
+		sig, _ := parser.Signature(op.Opcode)
+		for _, kind := range []byte(sig) {
+			if kind == 'N' {
+				b.WriteByte(parser.PositionChar(op.Positions[p]))
+				p++
+				continue
+			}
+			if c == 0 && johnClasses[op.Opcode] && op.Chars[c] == '?' {
+				// a single ? starts a character class in John
+				b.WriteString("??")
+			} else {
+				b.WriteString(JohnChar(op.Chars[c]))
+			}
+			c++
+		}
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("%w in john: %q", ErrUnsupported, op.String())
+}
+
+// JohnChar escapes a character for use in a John the Ripper rule
+//
+// # The preprocessor characters [, ], and \ are escaped and non-printable
+// bytes are written in \xNN format
+//
+// Args:
+//
+//	c (byte): Character to escape
+//
+// Returns:
+//
+//	(string): Escaped character
+func JohnChar(c byte) string {
+	switch {
+	case c == '\\' || c == '[' || c == ']':
+		return "\\" + string(c)
+	case c < 0x20 || c > 0x7e:
+		return fmt.Sprintf("\\x%02X", c)
+	}
+	return string(c)
+}
+
+// JohnString quotes a string for a John the Ripper A command
+//
+// # The first delimiter not found in the string is used
+//
+// Args:
+//
+//	s ([]byte): String to quote
+//
+// Returns:
+//
+//	(string): Quoted and escaped string
+//	(bool): If a delimiter not in the string was found
+func JohnString(s []byte) (string, bool) {
+	for _, delimiter := range []byte("\"'/|#!,;~") {
+		if strings.IndexByte(string(s), delimiter) != -1 {
+			continue
+		}
+		var b strings.Builder
+		b.WriteByte(delimiter)
+		for _, c := range s {
+			b.WriteString(JohnChar(c))
+		}
+		b.WriteByte(delimiter)
+		return b.String(), true
+	}
+	return "", false
+}
+
+// Translate converts a John the Ripper rule line into Hashcat syntax
+//
+// # Preprocessor ranges, rejection flags, character classes, and functions
+// without a Hashcat equivalent return an error
+//
+// Args:
+//
+//	rule (string): John the Ripper rule line
+//
+// Returns:
+//
+//	(string): Hashcat rule line
+//	(error): Error if the rule has no faithful translation
+func (John) Translate(rule string) (string, error) {
+	ops, err := parseJohn(rule)
+	if err != nil {
+		return "", err
+	}
+	if len(ops) == 0 {
+		return ":", nil
+	}
+	return parser.Format(ops), nil
+}
+
+// johnReader reads John the Ripper rule text one argument at a time
+type johnReader struct {
+	rule string
+	i    int
+}
+
+// char reads a literal character argument resolving escapes
+//
+// Returns:
+//
+//	(byte): Character value
+//	(error): Error if the argument is missing or uses the preprocessor
+func (r *johnReader) char() (byte, error) {
+	if r.i >= len(r.rule) {
+		return 0, fmt.Errorf("missing argument at column %d", r.i+1)
+	}
+	c := r.rule[r.i]
+	if c == '[' {
+		return 0, fmt.Errorf("%w in hashcat: preprocessor range at column %d", ErrUnsupported, r.i+1)
+	}
+	if c != '\\' || r.i+1 >= len(r.rule) {
+		r.i++
+		return c, nil
+	}
+
+	next := r.rule[r.i+1]
+	if next == 'x' && r.i+3 < len(r.rule) {
+		if v, err := strconv.ParseUint(r.rule[r.i+2:r.i+4], 16, 8); err == nil {
+			r.i += 4
+			return byte(v), nil
+		}
+	}
+	if next == 'p' || (next >= '0' && next <= '9') {
+		return 0, fmt.Errorf("%w in hashcat: preprocessor reference at column %d", ErrUnsupported, r.i+1)
+	}
+	r.i += 2
+	return next, nil
+}
+
+// position reads a numeric position argument
+//
+// Returns:
+//
+//	(int): Position value
+//	(error): Error if the argument is missing or is a variable position
+func (r *johnReader) position() (int, error) {
+	if r.i >= len(r.rule) {
+		return 0, fmt.Errorf("missing argument at column %d", r.i+1)
+	}
+	p, ok := parser.PositionValue(r.rule[r.i])
+	if !ok {
+		return 0, fmt.Errorf("%w in hashcat: position %q at column %d", ErrUnsupported, r.rule[r.i], r.i+1)
+	}
+	r.i++
+	return p, nil
+}
+
+// parseJohn parses a John the Ripper rule line into Hashcat operations
+//
+// Args:
+//
+//	rule (string): John the Ripper rule line
+//
+// Returns:
+//
+//	([]parser.Operation): Equivalent Hashcat operations
+//	(error): Error if the rule has no faithful translation
+func parseJohn(rule string) ([]parser.Operation, error) {
+	var ops []parser.Operation
+	r := &johnReader{rule: rule}
+	for r.i < len(rule) {
+		c := rule[r.i]
+		column := r.i + 1
+		switch {
+		case c == ' ' || c == '\t':
+			r.i++
+		case c == '\\' && r.i+1 < len(rule) && (rule[r.i+1] == '[' || rule[r.i+1] == ']'):
+			ops = append(ops, parser.Operation{Opcode: rule[r.i+1], Column: column})
+			r.i += 2
+		case c == ']':
+			ops = append(ops, parser.Operation{Opcode: ']', Column: column})
+			r.i++
+		case c == '[':
+			return nil, fmt.Errorf("%w in hashcat: preprocessor range at column %d", ErrUnsupported, column)
+		case c == '-':
+			return nil, fmt.Errorf("%w in hashcat: rejection flag at column %d", ErrUnsupported, column)
+		case c == 'A':
+			strOps, err := r.stringCommand()
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, strOps...)
+		case c == '<' || c == '>':
+			r.i++
+			p, err := r.position()
+			if err != nil {
+				return nil, err
+			}
+			// Hashcat allows N where John rejects unless shorter or longer than N
+			if c == '<' && p > 0 {
+				ops = append(ops, parser.Operation{Opcode: '<', Positions: []int{p - 1}, Column: column})
+			} else if c == '>' && p < parser.MaxPosition {
+				ops = append(ops, parser.Operation{Opcode: '>', Positions: []int{p + 1}, Column: column})
+			} else {
+				return nil, fmt.Errorf("%w in hashcat: %c%c at column %d", ErrUnsupported, c, rule[r.i-1], column)
+			}
+		case c == 'i' && r.i+1 < len(rule) && rule[r.i+1] == 'z':
+			r.i += 2
+			ch, err := r.char()
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, parser.Operation{Opcode: '$', Chars: []byte{ch}, Column: column})
+		case johnSame[c] || c == '$' || c == '^':
+			r.i++
+			op := parser.Operation{Opcode: c, Column: column}
+			sig, _ := parser.Signature(c)
+			for _, kind := range []byte(sig) {
+				if kind == 'N' {
+					p, err := r.position()
+					if err != nil {
+						return nil, err
+					}
+					op.Positions = append(op.Positions, p)
+					continue
+				}
+				if johnClasses[c] && len(op.Chars) == 0 && r.i < len(rule) && rule[r.i] == '?' {
+					if r.i+1 < len(rule) && rule[r.i+1] != '?' {
+						return nil, fmt.Errorf("%w in hashcat: character class at column %d", ErrUnsupported, r.i+1)
+					}
+					r.i++
+				}
+				ch, err := r.char()
+				if err != nil {
+					return nil, err
+				}
+				op.Chars = append(op.Chars, ch)
+			}
+			ops = append(ops, op)
+		default:
+			return nil, fmt.Errorf("%w in hashcat: %q at column %d", ErrUnsupported, c, column)
+		}
+	}
+	return ops, nil
+}
+
+// stringCommand reads an AN"..." command and returns equivalent operations
+//
+// Returns:
+//
+//	([]parser.Operation): Appends, prepends, or inserts for each character
+//	(error): Error if the command is malformed or cannot be expressed
+func (r *johnReader) stringCommand() ([]parser.Operation, error) {
+	column := r.i + 1
+	r.i++
+	if r.i >= len(r.rule) {
+		return nil, fmt.Errorf("missing argument at column %d", r.i+1)
+	}
+
+	where := r.rule[r.i]
+	start := 0
+	if where == 'z' {
+		r.i++
+	} else {
+		p, err := r.position()
+		if err != nil {
+			return nil, err
+		}
+		start = p
+	}
+
+	if r.i >= len(r.rule) {
+		return nil, fmt.Errorf("missing argument at column %d", r.i+1)
+	}
+	delimiter := r.rule[r.i]
+	r.i++
+	var text []byte
+	for {
+		if r.i >= len(r.rule) {
+			return nil, fmt.Errorf("unterminated string at column %d", column)
+		}
+		if r.rule[r.i] == delimiter {
+			r.i++
+			break
+		}
+		c, err := r.char()
+		if err != nil {
+			return nil, err
+		}
+		text = append(text, c)
+	}
+
+	var ops []parser.Operation
+	switch {
+	case where == 'z':
+		for _, c := range text {
+			ops = append(ops, parser.Operation{Opcode: '$', Chars: []byte{c}, Column: column})
+		}
+	case start == 0:
+		for j := len(text) - 1; j >= 0; j-- {
+			ops = append(ops, parser.Operation{Opcode: '^', Chars: []byte{text[j]}, Column: column})
+		}
+	default:
+		if start+len(text)-1 > parser.MaxPosition {
+			return nil, fmt.Errorf("%w in hashcat: insert past position Z at column %d", ErrUnsupported, column)
+		}
+		for j, c := range text {
+			ops = append(ops, parser.Operation{Opcode: 'i', Positions: []int{start + j}, Chars: []byte{c}, Column: column})
+		}
+	}
+	return ops, nil
+}
+
+// ConvertRules converts rule lines from one dialect to another and prints them
+//
+// # Lines without a faithful translation are reported to stderr with their
+// line number instead of being printed
+//
+// Args:
+//
+//	file ([]byte): Lines of a rule file
+//	w (io.Writer): Destination for the output
+//	from (Dialect): Syntax of the input rules
+//	to (Dialect): Syntax of the output rules
+//
+// Returns:
+//
+//	(int): Number of lines that could not be converted
+func ConvertRules(file []byte, w io.Writer, from Dialect, to Dialect) int {
+	failed := 0
+	if header := to.Header(); header != "" {
+		fmt.Fprintln(w, header)
+	}
+	for i, line := range strings.Split(string(file), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[List.") || strings.HasPrefix(line, "!!") {
+			continue
+		}
+
+		hashcat, err := from.Translate(line)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "line %d: %q: %s\n", i+1, line, err)
+			failed++
+			continue
+		}
+		output, err := to.Render(hashcat)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "line %d: %q: %s\n", i+1, line, err)
+			failed++
+			continue
+		}
+		fmt.Fprintln(w, output)
+	}
+	return failed
+}
@@ -0,0 +1,116 @@
+package dialect
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJohnRender(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"$1 $2 $3", "Az\"123\""},
+		{"^3 ^2 ^1", "A0\"123\""},
+		{"$1", "$1"},
+		{"] ] $a $b", "\\] \\] Az\"ab\""},
+		{"[ i0a", "\\[ i0a"},
+		{"$[ $\\", "Az\"\\[\\\\\""},
+		{"$\\xC3 $\\xA9", "Az\"\\xC3\\xA9\""},
+		{"$\" $a", "Az'\"a'"},
+		{"T0 T5 sa@", "T0 T5 sa@"},
+		{"<8 >4", "<9 >3"},
+		{"p1", "d"},
+		{"s?a", "s??a"},
+		{"sa?", "sa?"},
+		{"s??", "s???"},
+		{"@?", "@??"},
+		{"!?", "!??"},
+		{"/?", "/??"},
+		{"", ":"},
+	}
+
+	j := John{Section: "test"}
+	for _, test := range tests {
+		got, err := j.Render(test.rule)
+		if err != nil {
+			t.Errorf("Render(%q) returned error %v", test.rule, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Render(%q) = %q; want %q", test.rule, got, test.want)
+		}
+	}
+}
+
+func TestJohnRenderUnsupported(t *testing.T) {
+	tests := []string{"p2", "k", "L1", "z2", "E", "M $1 4"}
+
+	j := John{Section: "test"}
+	for _, rule := range tests {
+		if _, err := j.Render(rule); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Render(%q) error = %v; want ErrUnsupported", rule, err)
+		}
+	}
+}
+
+func TestJohnTranslate(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"Az\"123\"", "$1 $2 $3"},
+		{"A0\"123\"", "^3 ^2 ^1"},
+		{"A3'ab'", "i3a i4b"},
+		{"\\] \\] Az\"ab\"", "] ] $a $b"},
+		{"\\[ i0a", "[ i0a"},
+		{"Az\"\\[\\\\\"", "$[ $\\"},
+		{"Az\"\\xC3\\xA9\"", "$\\xC3 $\\xA9"},
+		{"T0 T5 sa@", "T0 T5 sa@"},
+		{"<9 >3", "<8 >4"},
+		{"iz!", "$!"},
+		{"s??!", "s?!"},
+		{"sa?", "sa?"},
+		{":", ":"},
+	}
+
+	j := John{Section: "test"}
+	for _, test := range tests {
+		got, err := j.Translate(test.rule)
+		if err != nil {
+			t.Errorf("Translate(%q) returned error %v", test.rule, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Translate(%q) = %q; want %q", test.rule, got, test.want)
+		}
+	}
+}
+
+func TestJohnTranslateUnsupported(t *testing.T) {
+	tests := []string{"$[0-9]", "Az\"[a-z]\"", "-c u", "s?vX", "p", "M", "il!", "\\p[ab]"}
+
+	j := John{Section: "test"}
+	for _, rule := range tests {
+		if _, err := j.Translate(rule); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Translate(%q) error = %v; want ErrUnsupported", rule, err)
+		}
+	}
+}
+
+func TestJohnRoundTrip(t *testing.T) {
+	tests := []string{"s?a", "s??", "sa?", "@?", "!?", "/?", "(? )? =1? %2?", "T0 sa@ $1"}
+
+	j := John{Section: "test"}
+	for _, rule := range tests {
+		john, err := j.Render(rule)
+		if err != nil {
+			t.Errorf("Render(%q) returned error %v", rule, err)
+			continue
+		}
+		got, err := j.Translate(john)
+		if err != nil || got != rule {
+			t.Errorf("Translate(Render(%q)) = %q, %v; want %q", rule, got, err, rule)
+		}
+	}
+}
@@ -0,0 +1,434 @@
+// Package engine contains the logic for applying Hashcat rules to text
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jakewnuk/rulecat/pkg/parser"
+	"github.com/jakewnuk/rulecat/pkg/utils"
+)
+
+// MaxWordLength is the longest candidate a rule is allowed to produce
+//
+// Functions that would grow a word past this length leave it unchanged
+const MaxWordLength = 256
+
+// ErrRejected is returned when a rejection function discards a word
+var ErrRejected = errors.New("word rejected by rule")
+
+// Rule is a compiled Hashcat rule that can be applied to words
+type Rule struct {
+	text string
+	ops  []parser.Operation
+}
+
+// Compile parses a rule line into a Rule
+//
+// Args:
+//
+//	rule (string): Rule line to parse
+//
+// Returns:
+//
+//	(*Rule): Compiled rule
+//	(error): Error if the rule is malformed
+func Compile(rule string) (*Rule, error) {
+	ops, err := parser.Parse(rule)
+	if err != nil {
+		return nil, err
+	}
+	return &Rule{text: rule, ops: ops}, nil
+}
+
+// String returns the original text of the rule
+func (r *Rule) String() string {
+	return r.text
+}
+
+// Apply applies a rule line to a word
+//
+// Args:
+//
+//	word (string): Word to transform
+//	rule (string): Rule line to apply
+//
+// Returns:
+//
+//	(string): Transformed word
+//	(error): Error if the rule is malformed or ErrRejected
+func Apply(word string, rule string) (string, error) {
+	r, err := Compile(rule)
+	if err != nil {
+		return "", err
+	}
+	return r.Apply(word)
+}
+
+// Apply applies the compiled rule to a word
+//
+// Args:
+//
+//	word (string): Word to transform
+//
+// Returns:
+//
+//	(string): Transformed word
+//	(error): ErrRejected if a rejection function discarded the word
+func (r *Rule) Apply(word string) (string, error) {
+	w := []byte(word)
+	mem := []byte(word)
+
+	for _, op := range r.ops {
+		next, err := execute(op, w, &mem)
+		if err != nil {
+			return "", err
+		}
+		if len(next) <= MaxWordLength {
+			w = next
+		}
+	}
+	return string(w), nil
+}
+
+// execute runs a single rule operation against a word
+//
+// Args:
+//
+//	f (parser.Operation): Operation to run
+//	w ([]byte): Current word
+//	mem (*[]byte): Memorized word used by memory functions
+//
+// Returns:
+//
+//	([]byte): Transformed word
+//	(error): ErrRejected if the word was rejected
+func execute(f parser.Operation, w []byte, mem *[]byte) ([]byte, error) {
+	n := len(w)
+	switch f.Opcode {
+	case ':':
+	case 'l':
+		return bytes.Map(lowerASCII, w), nil
+	case 'u':
+		return bytes.Map(upperASCII, w), nil
+	case 'c':
+		out := bytes.Map(lowerASCII, w)
+		if n > 0 {
+			out[0] = byte(upperASCII(rune(out[0])))
+		}
+		return out, nil
+	case 'C':
+		out := bytes.Map(upperASCII, w)
+		if n > 0 {
+			out[0] = byte(lowerASCII(rune(out[0])))
+		}
+		return out, nil
+	case 't':
+		return bytes.Map(toggleASCII, w), nil
+	case 'T':
+		if f.Positions[0] < n {
+			w[f.Positions[0]] = byte(toggleASCII(rune(w[f.Positions[0]])))
+		}
+	case 'r':
+		return reverse(w), nil
+	case 'd':
+		return concat(w, w), nil
+	case 'p':
+		return bytes.Repeat(w, f.Positions[0]+1), nil
+	case 'f':
+		return concat(w, reverse(w)), nil
+	case '{':
+		if n > 0 {
+			return concat(w[1:], w[:1]), nil
+		}
+	case '}':
+		if n > 0 {
+			return concat(w[n-1:], w[:n-1]), nil
+		}
+	case '$':
+		return concat(w, f.Chars), nil
+	case '^':
+		return concat(f.Chars, w), nil
+	case '[':
+		if n > 0 {
+			return w[1:], nil
+		}
+	case ']':
+		if n > 0 {
+			return w[:n-1], nil
+		}
+	case 'D':
+		if f.Positions[0] < n {
+			return concat(w[:f.Positions[0]], w[f.Positions[0]+1:]), nil
+		}
+	case 'x':
+		if f.Positions[0] < n && f.Positions[0]+f.Positions[1] <= n {
+			return w[f.Positions[0] : f.Positions[0]+f.Positions[1]], nil
+		}
+	case 'O':
+		if f.Positions[0] < n && f.Positions[0]+f.Positions[1] <= n {
+			return concat(w[:f.Positions[0]], w[f.Positions[0]+f.Positions[1]:]), nil
+		}
+	case 'i':
+		if f.Positions[0] <= n {
+			return concat(w[:f.Positions[0]], f.Chars, w[f.Positions[0]:]), nil
+		}
+	case 'o':
+		if f.Positions[0] < n {
+			w[f.Positions[0]] = f.Chars[0]
+		}
+	case '\'':
+		if f.Positions[0] < n {
+			return w[:f.Positions[0]], nil
+		}
+	case 's':
+		return bytes.ReplaceAll(w, f.Chars[:1], f.Chars[1:]), nil
+	case '@':
+		return bytes.ReplaceAll(w, f.Chars, nil), nil
+	case 'z':
+		if n > 0 {
+			return concat(bytes.Repeat(w[:1], f.Positions[0]), w), nil
+		}
+	case 'Z':
+		if n > 0 {
+			return concat(w, bytes.Repeat(w[n-1:], f.Positions[0])), nil
+		}
+	case 'q':
+		out := make([]byte, 0, n*2)
+		for _, b := range w {
+			out = append(out, b, b)
+		}
+		return out, nil
+	case 'y':
+		if f.Positions[0] <= n {
+			return concat(w[:f.Positions[0]], w), nil
+		}
+	case 'Y':
+		if f.Positions[0] <= n {
+			return concat(w, w[n-f.Positions[0]:]), nil
+		}
+	case '*':
+		if f.Positions[0] < n && f.Positions[1] < n {
+			w[f.Positions[0]], w[f.Positions[1]] = w[f.Positions[1]], w[f.Positions[0]]
+		}
+	case 'k':
+		if n >= 2 {
+			w[0], w[1] = w[1], w[0]
+		}
+	case 'K':
+		if n >= 2 {
+			w[n-1], w[n-2] = w[n-2], w[n-1]
+		}
+	case 'L':
+		if f.Positions[0] < n {
+			w[f.Positions[0]] <<= 1
+		}
+	case 'R':
+		if f.Positions[0] < n {
+			w[f.Positions[0]] >>= 1
+		}
+	case '+':
+		if f.Positions[0] < n {
+			w[f.Positions[0]]++
+		}
+	case '-':
+		if f.Positions[0] < n {
+			w[f.Positions[0]]--
+		}
+	case '.':
+		if f.Positions[0]+1 < n {
+			w[f.Positions[0]] = w[f.Positions[0]+1]
+		}
+	case ',':
+		if f.Positions[0] >= 1 && f.Positions[0] < n {
+			w[f.Positions[0]] = w[f.Positions[0]-1]
+		}
+	case 'E':
+		return titleCase(w, ' '), nil
+	case 'e':
+		return titleCase(w, f.Chars[0]), nil
+	case '3':
+		occurrence := 0
+		for i, b := range w {
+			if b != f.Chars[0] {
+				continue
+			}
+			if occurrence == f.Positions[0] {
+				if i+1 < n {
+					w[i+1] = byte(toggleASCII(rune(w[i+1])))
+				}
+				break
+			}
+			occurrence++
+		}
+	case 'M':
+		*mem = append([]byte(nil), w...)
+	case '4':
+		return concat(w, *mem), nil
+	case '6':
+		return concat(*mem, w), nil
+	case 'X':
+		start, length, at := f.Positions[0], f.Positions[1], f.Positions[2]
+		if start+length <= len(*mem) && at <= n {
+			return concat(w[:at], (*mem)[start:start+length], w[at:]), nil
+		}
+	case 'Q':
+		if bytes.Equal(w, *mem) {
+			return nil, ErrRejected
+		}
+	case '<':
+		if n > f.Positions[0] {
+			return nil, ErrRejected
+		}
+	case '>':
+		if n < f.Positions[0] {
+			return nil, ErrRejected
+		}
+	case '_':
+		if n != f.Positions[0] {
+			return nil, ErrRejected
+		}
+	case '!':
+		if bytes.IndexByte(w, f.Chars[0]) != -1 {
+			return nil, ErrRejected
+		}
+	case '/':
+		if bytes.IndexByte(w, f.Chars[0]) == -1 {
+			return nil, ErrRejected
+		}
+	case '(':
+		if n == 0 || w[0] != f.Chars[0] {
+			return nil, ErrRejected
+		}
+	case ')':
+		if n == 0 || w[n-1] != f.Chars[0] {
+			return nil, ErrRejected
+		}
+	case '=':
+		if f.Positions[0] >= n || w[f.Positions[0]] != f.Chars[0] {
+			return nil, ErrRejected
+		}
+	case '%':
+		if bytes.Count(w, f.Chars) < f.Positions[0] {
+			return nil, ErrRejected
+		}
+	}
+	return w, nil
+}
+
+// concat joins byte slices into a newly allocated slice
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// reverse returns a reversed copy of a byte slice
+func reverse(w []byte) []byte {
+	out := make([]byte, len(w))
+	for i, b := range w {
+		out[len(w)-1-i] = b
+	}
+	return out
+}
+
+// titleCase lowercases a word and uppercases the first character and every
+// character following the separator
+func titleCase(w []byte, sep byte) []byte {
+	out := bytes.Map(lowerASCII, w)
+	for i := range out {
+		if i == 0 || out[i-1] == sep {
+			out[i] = byte(upperASCII(rune(out[i])))
+		}
+	}
+	return out
+}
+
+// lowerASCII lowercases ASCII letters only like Hashcat
+func lowerASCII(r rune) rune {
+	if r >= 'A' && r <= 'Z' {
+		return r + 32
+	}
+	return r
+}
+
+// upperASCII uppercases ASCII letters only like Hashcat
+func upperASCII(r rune) rune {
+	if r >= 'a' && r <= 'z' {
+		return r - 32
+	}
+	return r
+}
+
+// toggleASCII toggles the case of ASCII letters only like Hashcat
+func toggleASCII(r rune) rune {
+	if r >= 'a' && r <= 'z' {
+		return r - 32
+	}
+	if r >= 'A' && r <= 'Z' {
+		return r + 32
+	}
+	return r
+}
+
+// CompileFile compiles every rule in a rule file
+//
+// # Rules that cannot be parsed are reported to stderr and skipped. Empty
+// lines and lines starting with # are ignored
+//
+// Args:
+//
+//	file ([]byte): Lines of a rule file
+//
+// Returns:
+//
+//	([]*Rule): Compiled rules in file order
+func CompileFile(file []byte) []*Rule {
+	var rules []*Rule
+	for i, line := range strings.Split(string(file), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r, err := Compile(line)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: skipping line %d: %s\n", i+1, err)
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// ApplyRules applies every rule in a file to each word from stdin
+//
+// # Rules that cannot be parsed are reported and skipped
+//
+// Args:
+//
+//	stdIn (*bufio.Scanner): Standard input as a buffer
+//	w (io.Writer): Destination for the output
+//	file ([]byte): Lines of a rule file
+//	hex (bool): If candidates that are not printable are written as $HEX[...]
+//
+// Returns:
+//
+//	None
+func ApplyRules(stdIn *bufio.Scanner, w io.Writer, file []byte, hex bool) {
+	rules := CompileFile(file)
+	for stdIn.Scan() {
+		for _, r := range rules {
+			candidate, err := r.Apply(stdIn.Text())
+			if err != nil {
+				continue
+			}
+			if hex {
+				candidate = utils.FormatPlaintext(candidate)
+			}
+			fmt.Fprintln(w, candidate)
+		}
+	}
+}
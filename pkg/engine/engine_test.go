@@ -0,0 +1,112 @@
+package engine
+
+import "testing"
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		word string
+		rule string
+		want string
+	}{
+		{"p@ssW0rd", ":", "p@ssW0rd"},
+		{"p@ssW0rd", "l", "p@ssw0rd"},
+		{"p@ssW0rd", "u", "P@SSW0RD"},
+		{"p@ssW0rd", "c", "P@ssw0rd"},
+		{"p@ssW0rd", "C", "p@SSW0RD"},
+		{"p@ssW0rd", "t", "P@SSw0RD"},
+		{"p@ssW0rd", "T3", "p@sSW0rd"},
+		{"p@ssW0rd", "r", "dr0Wss@p"},
+		{"p@ssW0rd", "d", "p@ssW0rdp@ssW0rd"},
+		{"p@ssW0rd", "p2", "p@ssW0rdp@ssW0rdp@ssW0rd"},
+		{"p@ssW0rd", "f", "p@ssW0rddr0Wss@p"},
+		{"p@ssW0rd", "{", "@ssW0rdp"},
+		{"p@ssW0rd", "}", "dp@ssW0r"},
+		{"p@ssW0rd", "$1", "p@ssW0rd1"},
+		{"p@ssW0rd", "^1", "1p@ssW0rd"},
+		{"p@ssW0rd", "[", "@ssW0rd"},
+		{"p@ssW0rd", "]", "p@ssW0r"},
+		{"p@ssW0rd", "D3", "p@sW0rd"},
+		{"p@ssW0rd", "x04", "p@ss"},
+		{"p@ssW0rd", "O12", "psW0rd"},
+		{"p@ssW0rd", "i4!", "p@ss!W0rd"},
+		{"p@ssW0rd", "o3$", "p@s$W0rd"},
+		{"p@ssW0rd", "'6", "p@ssW0"},
+		{"p@ssW0rd", "ss$", "p@$$W0rd"},
+		{"p@ssW0rd", "@s", "p@W0rd"},
+		{"p@ssW0rd", "z2", "ppp@ssW0rd"},
+		{"p@ssW0rd", "Z2", "p@ssW0rddd"},
+		{"p@ssW0rd", "q", "pp@@ssssWW00rrdd"},
+		{"p@ssW0rd", "y2", "p@p@ssW0rd"},
+		{"p@ssW0rd", "Y2", "p@ssW0rdrd"},
+		{"p@ssW0rd", "*34", "p@sWs0rd"},
+		{"p@ssW0rd", "k", "@pssW0rd"},
+		{"p@ssW0rd", "K", "p@ssW0dr"},
+		{"p@ssW0rd", "R2", "p@9sW0rd"},
+		{"p@ssW0rd", "+2", "p@tsW0rd"},
+		{"p@ssW0rd", "-1", "p?ssW0rd"},
+		{"p@ssW0rd", ".1", "psssW0rd"},
+		{"p@ssW0rd", ",1", "ppssW0rd"},
+		{"p@ssW0rd w0rld", "E", "P@ssw0rd W0rld"},
+		{"p@ssW0rd-w0rld", "e-", "P@ssw0rd-W0rld"},
+		{"pass-word", "30-", "pass-Word"},
+		{"p@ssW0rd", "uMl4", "p@ssw0rdP@SSW0RD"},
+		{"p@ssW0rd", "rMr6", "dr0Wss@pp@ssW0rd"},
+		{"p@ssW0rd", "lMX428", "p@ssw0rdw0"},
+		{"hello", "$1 $2 $3", "hello123"},
+		{"hello", "$\\x41", "helloA"},
+		{"hello", "$ ", "hello "},
+		{"hello", "DZ", "hello"},
+		{"hello", "i5!", "hello!"},
+		{"hello", "i6!", "hello"},
+	}
+
+	for _, test := range tests {
+		got, err := Apply(test.word, test.rule)
+		if err != nil {
+			t.Errorf("Apply(%q, %q) returned error %v", test.word, test.rule, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Apply(%q, %q) = %q; want %q", test.word, test.rule, got, test.want)
+		}
+	}
+}
+
+func TestApplyReject(t *testing.T) {
+	tests := []struct {
+		word   string
+		rule   string
+		reject bool
+	}{
+		{"p@ssW0rd", "<8", false},
+		{"p@ssW0rd", "<7", true},
+		{"p@ssW0rd", ">8", false},
+		{"p@ssW0rd", ">9", true},
+		{"p@ssW0rd", "_8", false},
+		{"p@ssW0rd", "_7", true},
+		{"p@ssW0rd", "!@", true},
+		{"p@ssW0rd", "/@", false},
+		{"p@ssW0rd", "(p", false},
+		{"p@ssW0rd", ")p", true},
+		{"p@ssW0rd", "=1@", false},
+		{"p@ssW0rd", "%3s", true},
+		{"p@ssW0rd", "MQ", true},
+	}
+
+	for _, test := range tests {
+		_, err := Apply(test.word, test.rule)
+		if got := err == ErrRejected; got != test.reject {
+			t.Errorf("Apply(%q, %q) rejected = %v; want %v", test.word, test.rule, got, test.reject)
+		}
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	tests := []string{"$", "T", "Tz", "s1", "x0", "#", "$1 w"}
+
+	for _, rule := range tests {
+		if _, err := Compile(rule); err == nil {
+			t.Errorf("Compile(%q) returned no error", rule)
+		}
+	}
+}
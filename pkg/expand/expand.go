@@ -0,0 +1,320 @@
+// Package expand contains the logic for expanding John the Ripper
+// preprocessor ranges into flat rules
+package expand
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jakewnuk/rulecat/pkg/dialect"
+)
+
+// segment is a piece of a rule template
+type segment struct {
+	// literal is raw rule text when the segment is not a range
+	literal string
+	// chars are the characters of a range
+	chars []byte
+	// index is the position of a range among all ranges
+	index int
+	// master is the index of the range this one runs in parallel with or -1
+	master int
+	// ref is the index of the range this segment copies or -1
+	ref int
+}
+
+// Template is a parsed rule template with preprocessor ranges
+type Template struct {
+	segments []segment
+	// ranges are the indexes of range segments in order of appearance
+	ranges []int
+}
+
+// Parse parses a rule template with [...] ranges, \p[...] parallel ranges,
+// and \0-\9 back references
+//
+// Args:
+//
+//	template (string): John the Ripper rule template
+//
+// Returns:
+//
+//	(*Template): Parsed template
+//	(error): Error if a range is empty, unterminated, or refers to a missing
+//	range
+func Parse(template string) (*Template, error) {
+	t := &Template{}
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			t.segments = append(t.segments, segment{literal: literal.String(), master: -1, ref: -1})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		switch {
+		case c == '\\' && i+1 < len(template) && template[i+1] == 'p':
+			// \p[...] runs with the previous range and \pN[...] with range N
+			master := len(t.ranges) - 1
+			j := i + 2
+			if j < len(template) && template[j] >= '1' && template[j] <= '9' {
+				master = int(template[j] - '1')
+				j++
+			}
+			if j >= len(template) || template[j] != '[' {
+				return nil, fmt.Errorf("column %d: \\p must be followed by a range", i+1)
+			}
+			if master < 0 || master >= len(t.ranges) {
+				return nil, fmt.Errorf("column %d: parallel range has no range to follow", i+1)
+			}
+			flush()
+			chars, end, err := parseRange(template, j)
+			if err != nil {
+				return nil, err
+			}
+			t.ranges = append(t.ranges, len(t.segments))
+			t.segments = append(t.segments, segment{chars: chars, index: len(t.ranges) - 1, master: t.root(master), ref: -1})
+			i = end
+		case c == '\\' && i+1 < len(template) && template[i+1] >= '0' && template[i+1] <= '9':
+			// \0 copies the previous range and \N copies range N
+			ref := len(t.ranges) - 1
+			if template[i+1] != '0' {
+				ref = int(template[i+1] - '1')
+			}
+			if ref < 0 || ref >= len(t.ranges) {
+				return nil, fmt.Errorf("column %d: back reference to a missing range", i+1)
+			}
+			flush()
+			t.segments = append(t.segments, segment{master: -1, ref: ref})
+			i++
+		case c == '\\' && i+1 < len(template):
+			literal.WriteString(template[i : i+2])
+			i++
+		case c == '[':
+			flush()
+			chars, end, err := parseRange(template, i)
+			if err != nil {
+				return nil, err
+			}
+			t.ranges = append(t.ranges, len(t.segments))
+			t.segments = append(t.segments, segment{chars: chars, index: len(t.ranges) - 1, master: -1, ref: -1})
+			i = end
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	flush()
+	return t, nil
+}
+
+// root follows parallel ranges back to the range that drives them
+func (t *Template) root(r int) int {
+	for t.segments[t.ranges[r]].master != -1 {
+		r = t.segments[t.ranges[r]].master
+	}
+	return r
+}
+
+// parseRange reads the characters of a [...] range
+//
+// Args:
+//
+//	s (string): Template text
+//	start (int): Index of the opening [
+//
+// Returns:
+//
+//	([]byte): Characters in the range in order
+//	(int): Index of the closing ]
+//	(error): Error if the range is empty or unterminated
+func parseRange(s string, start int) ([]byte, int, error) {
+	var items []byte
+	for i := start + 1; i < len(s); {
+		if s[i] == ']' {
+			if len(items) == 0 {
+				return nil, 0, fmt.Errorf("column %d: empty range", start+1)
+			}
+			return items, i, nil
+		}
+
+		if s[i] == '-' && len(items) > 0 && i+1 < len(s) && s[i+1] != ']' {
+			to, width := rangeChar(s, i+1)
+			for b := int(items[len(items)-1]) + 1; b <= int(to); b++ {
+				items = append(items, byte(b))
+			}
+			i += 1 + width
+			continue
+		}
+
+		c, width := rangeChar(s, i)
+		items = append(items, c)
+		i += width
+	}
+	return nil, 0, fmt.Errorf("column %d: unterminated range", start+1)
+}
+
+// rangeChar reads a single character in a range resolving escapes
+//
+// Args:
+//
+//	s (string): Template text
+//	i (int): Index of the character
+//
+// Returns:
+//
+//	(byte): Character value
+//	(int): Number of bytes consumed
+func rangeChar(s string, i int) (byte, int) {
+	if s[i] != '\\' || i+1 >= len(s) {
+		return s[i], 1
+	}
+	if s[i+1] == 'x' && i+3 < len(s) {
+		if v, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+			return byte(v), 4
+		}
+	}
+	return s[i+1], 2
+}
+
+// Count returns the number of rules the template expands into
+//
+// # The count is capped at math.MaxInt64
+//
+// Returns:
+//
+//	(int64): Number of rules
+func (t *Template) Count() int64 {
+	count := int64(1)
+	for _, idx := range t.ranges {
+		s := t.segments[idx]
+		if s.master != -1 {
+			continue
+		}
+		if count > math.MaxInt64/int64(len(s.chars)) {
+			return math.MaxInt64
+		}
+		count *= int64(len(s.chars))
+	}
+	return count
+}
+
+// Expand calls fn with every rule the template expands into
+//
+// # The rightmost range changes fastest
+//
+// Args:
+//
+//	fn (func(string) bool): Called with each John the Ripper rule and returns
+//	false to stop
+//
+// Returns:
+//
+//	None
+func (t *Template) Expand(fn func(string) bool) {
+	counters := make([]int, len(t.ranges))
+	for {
+		var b strings.Builder
+		for _, s := range t.segments {
+			switch {
+			case s.chars != nil:
+				b.WriteString(dialect.JohnChar(t.value(s, counters)))
+			case s.ref != -1:
+				b.WriteString(dialect.JohnChar(t.value(t.segments[t.ranges[s.ref]], counters)))
+			default:
+				b.WriteString(s.literal)
+			}
+		}
+		if !fn(b.String()) {
+			return
+		}
+
+		// advance the rightmost range that drives others
+		r := len(t.ranges) - 1
+		for ; r >= 0; r-- {
+			s := t.segments[t.ranges[r]]
+			if s.master != -1 {
+				continue
+			}
+			counters[r]++
+			if counters[r] < len(s.chars) {
+				break
+			}
+			counters[r] = 0
+		}
+		if r < 0 {
+			return
+		}
+	}
+}
+
+// value returns the current character of a range segment
+//
+// # Parallel ranges repeat when they are shorter than their driving range
+func (t *Template) value(s segment, counters []int) byte {
+	if s.master == -1 {
+		return s.chars[counters[s.index]]
+	}
+	return s.chars[counters[s.master]%len(s.chars)]
+}
+
+// ExpandRules expands rule templates from stdin into Hashcat rules
+//
+// # Expanded rules that cannot be translated are reported to stderr with the
+// line number of their template and are skipped
+//
+// Args:
+//
+//	stdIn (*bufio.Scanner): John the Ripper rule templates as a buffer
+//	w (io.Writer): Destination for the output
+//	countOnly (bool): Print only the number of rules that would be created
+//
+// Returns:
+//
+//	None
+func ExpandRules(stdIn *bufio.Scanner, w io.Writer, countOnly bool) {
+	john := dialect.John{}
+	total := int64(0)
+	line := 0
+	for stdIn.Scan() {
+		line++
+		text := stdIn.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		t, err := Parse(text)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "line %d: %q: %s\n", line, text, err)
+			continue
+		}
+		if countOnly {
+			if total > math.MaxInt64-t.Count() {
+				total = math.MaxInt64
+			} else {
+				total += t.Count()
+			}
+			continue
+		}
+
+		t.Expand(func(rule string) bool {
+			hashcat, err := john.Translate(rule)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "line %d: %q: %s\n", line, rule, err)
+				return true
+			}
+			fmt.Fprintln(w, hashcat)
+			return true
+		})
+	}
+
+	if countOnly {
+		fmt.Fprintln(w, total)
+	}
+}
@@ -0,0 +1,75 @@
+package expand
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		template string
+		want     []string
+	}{
+		{"$[0-2]", []string{"$0", "$1", "$2"}},
+		{"i[0-1][ab]", []string{"i0a", "i0b", "i1a", "i1b"}},
+		{"^[abc]$\\p[123]", []string{"^a$1", "^b$2", "^c$3"}},
+		{"o[0-2]\\p[xy]", []string{"o0x", "o1y", "o2x"}},
+		{"$[ab]$\\0$\\1", []string{"$a$a$a", "$b$b$b"}},
+		{"$[\\]\\\\]", []string{"$\\]", "$\\\\"}},
+		{"$[\\x41-\\x42]", []string{"$A", "$B"}},
+		{"\\[ c", []string{"\\[ c"}},
+	}
+
+	for _, test := range tests {
+		tmpl, err := Parse(test.template)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error %v", test.template, err)
+			continue
+		}
+		var got []string
+		tmpl.Expand(func(rule string) bool {
+			got = append(got, rule)
+			return true
+		})
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Expand(%q) = %q; want %q", test.template, got, test.want)
+		}
+		if tmpl.Count() != int64(len(test.want)) {
+			t.Errorf("Count(%q) = %d; want %d", test.template, tmpl.Count(), len(test.want))
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []string{"$[0-9", "$[]", "$\\p[12]", "$\\1"}
+
+	for _, template := range tests {
+		if _, err := Parse(template); err == nil {
+			t.Errorf("Parse(%q) returned no error", template)
+		}
+	}
+}
+
+func TestExpandRules(t *testing.T) {
+	tests := []struct {
+		templates string
+		countOnly bool
+		want      string
+	}{
+		{"$[12]\n# comment\n\n^[ab]\n", false, "$1\n$2\n^a\n^b\n"},
+		{"[M:]$[12]\n$3\n", false, ": $1\n: $2\n$3\n"},
+		{"[M:]$[12]\n$3\n", true, "5\n"},
+		{"$[0-9\n$1\n", false, "$1\n"},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		ExpandRules(bufio.NewScanner(strings.NewReader(test.templates)), &out, test.countOnly)
+		if out.String() != test.want {
+			t.Errorf("ExpandRules(%q, %v) wrote %q; want %q", test.templates, test.countOnly, out.String(), test.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package freq contains the logic for counting repeated output lines and
+// writing them by frequency
+package freq
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Options control how counted lines are written
+type Options struct {
+	// Sort writes lines from most to least frequent instead of first seen
+	Sort bool
+	// Counts writes each line as count<TAB>line
+	Counts bool
+	// Min is the fewest times a line must be seen to be written
+	Min int
+	// Header is a first line that is written as is instead of counted
+	Header string
+}
+
+// Writer is an io.Writer that counts lines and writes each once on Flush
+type Writer struct {
+	w    io.Writer
+	opts Options
+	// counts are the times each line was seen
+	counts map[string]int
+	// order are the lines in the order they were first seen
+	order []string
+	// partial is the start of a line that has no newline yet
+	partial []byte
+	// lines is the number of lines seen so far
+	lines int
+	// header is set if the first line was the header
+	header bool
+}
+
+// NewWriter creates a Writer that counts lines
+//
+// Args:
+//
+//	w (io.Writer): Destination for the counted lines
+//	opts (Options): How to write the counted lines
+//
+// Returns:
+//
+//	(*Writer): Writer that writes nothing until it is flushed
+func NewWriter(w io.Writer, opts Options) *Writer {
+	return &Writer{w: w, opts: opts, counts: make(map[string]int)}
+}
+
+// Write counts each complete line
+func (f *Writer) Write(p []byte) (int, error) {
+	rest := p
+	for {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break
+		}
+		line := rest[:i]
+		rest = rest[i+1:]
+		if len(f.partial) > 0 {
+			line = append(f.partial, line...)
+			f.partial = f.partial[:0]
+		}
+		f.add(string(line))
+	}
+	f.partial = append(f.partial, rest...)
+	return len(p), nil
+}
+
+// add counts a line
+func (f *Writer) add(line string) {
+	f.lines++
+	if f.lines == 1 && f.opts.Header != "" && line == f.opts.Header {
+		f.header = true
+		return
+	}
+	if _, ok := f.counts[line]; !ok {
+		f.order = append(f.order, line)
+	}
+	f.counts[line]++
+}
+
+// Flush writes every line seen at least Options.Min times
+//
+// # Lines seen the same number of times keep the order they were first
+// seen in
+func (f *Writer) Flush() error {
+	if len(f.partial) > 0 {
+		f.add(string(f.partial))
+		f.partial = nil
+	}
+
+	if f.opts.Sort {
+		sort.SliceStable(f.order, func(i, j int) bool {
+			return f.counts[f.order[i]] > f.counts[f.order[j]]
+		})
+	}
+
+	out := bufio.NewWriter(f.w)
+	if f.header {
+		fmt.Fprintln(out, f.opts.Header)
+	}
+	for _, line := range f.order {
+		count := f.counts[line]
+		if count < f.opts.Min {
+			continue
+		}
+		if f.opts.Counts {
+			fmt.Fprintf(out, "%d\t", count)
+		}
+		fmt.Fprintln(out, line)
+	}
+	f.counts = make(map[string]int)
+	f.order = nil
+	f.lines = 0
+	f.header = false
+	return out.Flush()
+}
@@ -0,0 +1,42 @@
+package freq
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	text := "$1\n$2\n$3\n$2\n$3\n$3\n$4\n$4"
+
+	tests := []struct {
+		name string
+		text string
+		opts Options
+		want string
+	}{
+		{"first seen", text, Options{}, "$1\n$2\n$3\n$4\n"},
+		{"counts", text, Options{Counts: true}, "1\t$1\n2\t$2\n3\t$3\n2\t$4\n"},
+		{"sort", text, Options{Sort: true, Counts: true}, "3\t$3\n2\t$2\n2\t$4\n1\t$1\n"},
+		{"min", text, Options{Sort: true, Min: 2}, "$3\n$2\n$4\n"},
+		{"header", "[List.Rules:test]\n" + text, Options{Sort: true, Min: 3, Header: "[List.Rules:test]"}, "[List.Rules:test]\n$3\n"},
+		{"empty", "", Options{Header: "[List.Rules:test]"}, ""},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		w := NewWriter(&out, test.opts)
+		// split the text to check lines written in parts
+		half := len(test.text) / 2
+		w.Write([]byte(test.text[:half]))
+		w.Write([]byte(test.text[half:]))
+		if out.Len() != 0 {
+			t.Errorf("%s: wrote %q before Flush", test.name, out.String())
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("%s: Flush() error = %v", test.name, err)
+		}
+		if out.String() != test.want {
+			t.Errorf("%s: wrote %q; want %q", test.name, out.String(), test.want)
+		}
+	}
+}
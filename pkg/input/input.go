@@ -0,0 +1,333 @@
+// Package input contains the logic for reading text from files, directories,
+// glob patterns, and stdin with transparent decompression and for decoding
+// $HEX[...] lines
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jakewnuk/rulecat/pkg/utils"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Stdin is the source name that reads from standard input
+const Stdin = "-"
+
+// magic numbers of the supported compression formats
+var (
+	gzipMagic  = []byte{0x1f, 0x8b, 0x08}
+	bzip2Magic = []byte("BZh")
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// bzip2Block is the start of the first bzip2 block after the level digit
+var bzip2Block = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+
+// Paths resolves sources into the files to read in order
+//
+// # Directories are walked recursively in lexical order and glob patterns
+// are expanded in lexical order. Sources that exist are not expanded as
+// patterns and the stdin source is kept as is
+//
+// Args:
+//
+//	sources ([]string): Files, directories, glob patterns, or "-"
+//
+// Returns:
+//
+//	([]string): Paths of regular files and "-"
+//	(error): Error if a source does not exist or a pattern matches nothing
+func Paths(sources []string) ([]string, error) {
+	var paths []string
+	for _, source := range sources {
+		if source == Stdin {
+			paths = append(paths, source)
+			continue
+		}
+
+		// an existing file is read as is even if its name looks like a pattern
+		matches := []string{source}
+		if _, err := os.Stat(source); err != nil && strings.ContainsAny(source, "*?[") {
+			var err error
+			matches, err = filepath.Glob(source)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", source, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("%s: no files match the pattern", source)
+			}
+			sort.Strings(matches)
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				paths = append(paths, match)
+				continue
+			}
+			err = filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.Type().IsRegular() {
+					paths = append(paths, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return paths, nil
+}
+
+// Open opens sources as a single stream of text
+//
+// # Files are opened one at a time as the stream is read and a newline is
+// added between files that do not end with one
+//
+// Args:
+//
+//	sources ([]string): Files, directories, glob patterns, or "-"
+//
+// Returns:
+//
+//	(io.ReadCloser): Text of every source in order
+//	(error): Error if a source cannot be resolved
+func Open(sources ...string) (io.ReadCloser, error) {
+	paths, err := Paths(sources)
+	if err != nil {
+		return nil, err
+	}
+	return &multiReader{paths: paths, last: '\n'}, nil
+}
+
+// ReadFile reads a whole file and decompresses it if needed
+//
+// Args:
+//
+//	path (string): Path of the file or "-" for stdin
+//
+// Returns:
+//
+//	([]byte): Decompressed contents
+//	(error): Error if the file cannot be read
+func ReadFile(path string) ([]byte, error) {
+	r, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// NewReader decompresses a stream that starts with a gzip, bzip2, xz, or
+// zstd header and passes other streams through unchanged
+//
+// Args:
+//
+//	r (io.Reader): Stream that may be compressed
+//
+// Returns:
+//
+//	(io.ReadCloser): Decompressed stream
+//	(error): Error if a compression header is invalid
+func NewReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(10)
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(header, bzip2Magic) && len(header) == 10 && header[3] >= '1' && header[3] <= '9' && bytes.Equal(header[4:], bzip2Block):
+		return io.NopCloser(bzip2.NewReader(br)), nil
+	case bytes.HasPrefix(header, xzMagic):
+		xr, err := xz.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xr), nil
+	case bytes.HasPrefix(header, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return io.NopCloser(br), nil
+}
+
+// DecodeLine decodes a line in the Hashcat $HEX[...] format
+//
+// # Other lines and lines with invalid hex are returned unchanged
+//
+// Args:
+//
+//	line (string): Line without its line ending
+//
+// Returns:
+//
+//	(string): Decoded line
+func DecodeLine(line string) string {
+	if !strings.HasPrefix(line, "$HEX[") {
+		return line
+	}
+	decoded, _ := utils.DecodeHexString(line)
+	return decoded
+}
+
+// ScanHexLines is a bufio.SplitFunc that splits lines like bufio.ScanLines
+// and decodes each line in the Hashcat $HEX[...] format
+//
+// # Lines are split before they are decoded so a decoded newline stays part
+// of its line
+//
+// Args:
+//
+//	data ([]byte): Unread input
+//	atEOF (bool): If there is no more input
+//
+// Returns:
+//
+//	(int): Number of bytes to advance the input
+//	([]byte): Decoded line or nil if more input is needed
+//	(error): Error from bufio.ScanLines
+func ScanHexLines(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	if token != nil && bytes.HasPrefix(token, []byte("$HEX[")) {
+		token = []byte(DecodeLine(string(token)))
+	}
+	return advance, token, err
+}
+
+// SplitWords splits file contents into non-empty lines and decodes lines in
+// the Hashcat $HEX[...] format
+//
+// Args:
+//
+//	file ([]byte): File contents
+//
+// Returns:
+//
+//	([]string): Decoded lines without line endings
+func SplitWords(file []byte) []string {
+	lines := utils.SplitLines(file)
+	for i, line := range lines {
+		lines[i] = DecodeLine(line)
+	}
+	return lines
+}
+
+// multiReader reads files one after another
+type multiReader struct {
+	paths []string
+	// current is the decompressed stream of the open file
+	current io.ReadCloser
+	// file is the open file or nil for stdin
+	file *os.File
+	// last is the last byte read from the stream
+	last byte
+}
+
+// Read reads from the current file and opens the next one when it ends
+func (m *multiReader) Read(p []byte) (int, error) {
+	for {
+		if m.current == nil {
+			if len(m.paths) == 0 {
+				return 0, io.EOF
+			}
+			if err := m.next(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := m.current.Read(p)
+		if n > 0 {
+			m.last = p[n-1]
+			return n, nil
+		}
+		if err == io.EOF {
+			if closeErr := m.closeCurrent(); closeErr != nil {
+				return 0, closeErr
+			}
+			if m.last != '\n' && len(p) > 0 {
+				p[0] = '\n'
+				m.last = '\n'
+				return 1, nil
+			}
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", m.name(), err)
+		}
+	}
+}
+
+// next opens the next path
+func (m *multiReader) next() error {
+	path := m.paths[0]
+	m.paths = m.paths[1:]
+
+	var r io.Reader = os.Stdin
+	if path != Stdin {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		m.file = file
+		r = file
+	}
+
+	current, err := NewReader(r)
+	if err != nil {
+		m.closeCurrent()
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	m.current = current
+	return nil
+}
+
+// name returns the name of the open file for errors
+func (m *multiReader) name() string {
+	if m.file == nil {
+		return "stdin"
+	}
+	return m.file.Name()
+}
+
+// closeCurrent closes the open file and its decompressor
+func (m *multiReader) closeCurrent() error {
+	var err error
+	if m.current != nil {
+		err = m.current.Close()
+		m.current = nil
+	}
+	if m.file != nil {
+		if closeErr := m.file.Close(); err == nil {
+			err = closeErr
+		}
+		m.file = nil
+	}
+	return err
+}
+
+// Close closes the open file
+func (m *multiReader) Close() error {
+	m.paths = nil
+	return m.closeCurrent()
+}
@@ -0,0 +1,197 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// bzip2Text is "abc\n123\n" compressed with bzip2
+var bzip2Text = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x21, 0xa9,
+	0x24, 0x5f, 0x00, 0x00, 0x02, 0xc9, 0x00, 0x00, 0x10, 0x38, 0x00, 0x38,
+	0x00, 0x20, 0x00, 0x22, 0x18, 0x02, 0x18, 0x0a, 0xca, 0x66, 0x5c, 0x2e,
+	0xe4, 0x8a, 0x70, 0xa1, 0x20, 0x43, 0x52, 0x48, 0xbe,
+}
+
+func compress(t *testing.T, format string, text string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	switch format {
+	case "gz":
+		w = gzip.NewWriter(&buf)
+	case "xz":
+		w, err = xz.NewWriter(&buf)
+	case "zst":
+		w, err = zstd.NewWriter(&buf)
+	case "bz2":
+		return bzip2Text
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, text); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestNewReader(t *testing.T) {
+	for _, format := range []string{"gz", "bz2", "xz", "zst", "txt"} {
+		data := []byte("abc\n123\n")
+		if format != "txt" {
+			data = compress(t, format, "abc\n123\n")
+		}
+
+		r, err := NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: NewReader() error = %v", format, err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: read error = %v", format, err)
+		}
+		r.Close()
+		if string(got) != "abc\n123\n" {
+			t.Errorf("%s: read %q; want %q", format, got, "abc\n123\n")
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"a.txt":       []byte("a1\na2"),
+		"b.gz":        compress(t, "gz", "b1\n"),
+		"sub/c.txt":   []byte("c1\n"),
+		"sub/d.zst":   compress(t, "zst", "d1\n"),
+		"other/e.txt": []byte("e1\n"),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		sources []string
+		want    string
+	}{
+		{"files", []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.gz")}, "a1\na2\nb1\n"},
+		{"directory", []string{filepath.Join(dir, "sub")}, "c1\nd1\n"},
+		{"glob", []string{filepath.Join(dir, "*.gz"), filepath.Join(dir, "other", "*")}, "b1\ne1\n"},
+	}
+
+	for _, test := range tests {
+		r, err := Open(test.sources...)
+		if err != nil {
+			t.Fatalf("%s: Open() error = %v", test.name, err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: read error = %v", test.name, err)
+		}
+		r.Close()
+		if string(got) != test.want {
+			t.Errorf("%s: read %q; want %q", test.name, got, test.want)
+		}
+	}
+
+	for _, sources := range [][]string{{filepath.Join(dir, "missing.txt")}, {filepath.Join(dir, "*.xz")}} {
+		if _, err := Open(sources...); err == nil {
+			t.Errorf("Open(%q) error = nil; want an error", sources)
+		}
+	}
+}
+
+func TestPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.txt", "a.txt", "w[1].txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		sources []string
+		want    []string
+	}{
+		{[]string{"-", dir}, []string{"-", filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt"), filepath.Join(dir, "w[1].txt")}},
+		{[]string{filepath.Join(dir, "w[1].txt")}, []string{filepath.Join(dir, "w[1].txt")}},
+		{[]string{filepath.Join(dir, "[ab].txt")}, []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}},
+	}
+
+	for _, test := range tests {
+		got, err := Paths(test.sources)
+		if err != nil {
+			t.Fatalf("Paths(%q) error = %v", test.sources, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Paths(%q) = %q; want %q", test.sources, got, test.want)
+		}
+	}
+}
+
+func TestScanHexLines(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"$HEX[70617373]\nword\n", []string{"pass", "word"}},
+		{"$HEX[fc6e69]\r\n$HEX[zz]\n$HEX[]\n", []string{"\xfcni", "$HEX[zz]", ""}},
+		{"a\n$HEX[6162]", []string{"a", "ab"}},
+		{"$HEX[610a62]\nc\n", []string{"a\nb", "c"}},
+	}
+
+	for _, test := range tests {
+		scanner := bufio.NewScanner(strings.NewReader(test.text))
+		scanner.Split(ScanHexLines)
+		var got []string
+		for scanner.Scan() {
+			got = append(got, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			t.Fatalf("ScanHexLines(%q) error = %v", test.text, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ScanHexLines(%q) = %q; want %q", test.text, got, test.want)
+		}
+	}
+
+	if got, want := SplitWords([]byte("$HEX[610a62]\r\n\nc\n")), []string{"a\nb", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitWords() = %q; want %q", got, want)
+	}
+}
+
+func TestOpenKeepsHex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.txt")
+	if err := os.WriteFile(path, []byte("$HEX[610a62]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(got) != "$HEX[610a62]\n" {
+		t.Errorf("ReadFile() = %q; want the file unchanged", got)
+	}
+}
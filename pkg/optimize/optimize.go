@@ -0,0 +1,291 @@
+// Package optimize contains the logic for rewriting rules into simpler
+// equivalent forms
+package optimize
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/jakewnuk/rulecat/pkg/parser"
+)
+
+// involutions are functions that cancel out when repeated back to back
+var involutions = map[byte]bool{'t': true, 'r': true, 'k': true, 'K': true}
+
+// idempotents are functions that have no extra effect when repeated
+var idempotents = map[byte]bool{
+	'l': true, 'u': true, 'c': true, 'C': true, 'E': true, 'e': true,
+	's': true, '@': true,
+}
+
+// caseSetters are functions that overwrite the case of the whole word
+var caseSetters = map[byte]bool{'l': true, 'u': true, 'c': true, 'C': true, 'E': true, 'e': true}
+
+// caseModifiers are functions that only change the case of letters
+var caseModifiers = map[byte]bool{
+	'l': true, 'u': true, 'c': true, 'C': true, 'E': true, 'e': true,
+	't': true, 'T': true,
+}
+
+// Normalize rewrites a rule line into its canonical form
+//
+// # No-ops are removed, paired functions cancel, and repeated functions
+// merge. Only simplifications that give the same result for every word
+// length are made, so consecutive deletes are kept as they are because an
+// out of range O leaves the word unchanged while each delete still applies
+//
+// Args:
+//
+//	rule (string): Rule line to normalize
+//
+// Returns:
+//
+//	(string): Normalized rule line or ":" if nothing remains
+//	(error): Error if the rule is malformed
+func Normalize(rule string) (string, error) {
+	ops, err := parser.Parse(rule)
+	if err != nil {
+		return "", err
+	}
+	return format(normalize(ops)), nil
+}
+
+// normalize applies the canonical simplifications to operations
+//
+// Args:
+//
+//	ops ([]parser.Operation): Operations to simplify
+//
+// Returns:
+//
+//	([]parser.Operation): Simplified operations
+func normalize(ops []parser.Operation) []parser.Operation {
+	var stack []parser.Operation
+	for _, op := range ops {
+		if isNoop(op) {
+			continue
+		}
+		stack = append(stack, op)
+		for len(stack) >= 2 {
+			merged, ok := combine(stack[len(stack)-2], stack[len(stack)-1])
+			if !ok {
+				break
+			}
+			stack = append(stack[:len(stack)-2], merged...)
+		}
+	}
+	return stack
+}
+
+// combine simplifies two adjacent operations when the result is the same
+// for every word
+//
+// Args:
+//
+//	a (parser.Operation): First operation
+//	b (parser.Operation): Second operation
+//
+// Returns:
+//
+//	([]parser.Operation): Replacement operations
+//	(bool): If the pair was simplified
+func combine(a parser.Operation, b parser.Operation) ([]parser.Operation, bool) {
+	switch {
+	case same(a, b) && involutions[a.Opcode]:
+		return nil, true
+	case same(a, b) && a.Opcode == 'T':
+		return nil, true
+	case same(a, b) && idempotents[a.Opcode]:
+		return []parser.Operation{a}, true
+	case caseModifiers[a.Opcode] && caseSetters[b.Opcode]:
+		return []parser.Operation{b}, true
+	case (a.Opcode == '{' && b.Opcode == '}') || (a.Opcode == '}' && b.Opcode == '{'):
+		return nil, true
+	}
+	return nil, false
+}
+
+// Optimize rewrites a rule line into a shorter equivalent form
+//
+// # Only rewrites that give the same result for words of every length are
+// made so some rules such as ] ] ] are left as they are
+//
+// Args:
+//
+//	rule (string): Rule line to optimize
+//
+// Returns:
+//
+//	(string): Optimized rule line or ":" if nothing remains
+//	(error): Error if the rule is malformed
+func Optimize(rule string) (string, error) {
+	ops, err := parser.Parse(rule)
+	if err != nil {
+		return "", err
+	}
+	return format(optimize(ops)), nil
+}
+
+// optimize normalizes and rewrites operations until nothing changes
+//
+// Args:
+//
+//	ops ([]parser.Operation): Operations to optimize
+//
+// Returns:
+//
+//	([]parser.Operation): Optimized operations
+func optimize(ops []parser.Operation) []parser.Operation {
+	current := parser.Format(ops)
+	for {
+		ops = rewrite(normalize(ops))
+		next := parser.Format(ops)
+		if next == current {
+			return ops
+		}
+		current = next
+	}
+}
+
+// rewrite replaces runs of operations with shorter equivalents
+//
+// Args:
+//
+//	ops ([]parser.Operation): Operations to rewrite
+//
+// Returns:
+//
+//	([]parser.Operation): Rewritten operations
+func rewrite(ops []parser.Operation) []parser.Operation {
+	var out []parser.Operation
+	for i := 0; i < len(ops); i++ {
+		op := ops[i]
+
+		if i+2 < len(ops) && ops[i].Opcode == 'r' && ops[i+2].Opcode == 'r' {
+			if mirrored, ok := mirror(ops[i+1]); ok {
+				out = append(out, mirrored)
+				i += 2
+				continue
+			}
+		}
+
+		if i+1 < len(ops) {
+			if merged, ok := rewritePair(op, ops[i+1]); ok {
+				out = append(out, merged)
+				i++
+				continue
+			}
+		}
+
+		out = append(out, rewriteSingle(op))
+	}
+	return out
+}
+
+// rewriteSingle replaces an operation with a shorter equivalent
+func rewriteSingle(op parser.Operation) parser.Operation {
+	switch {
+	case op.Opcode == 'D' && op.Positions[0] == 0:
+		return parser.Operation{Opcode: '['}
+	case op.Opcode == 'O' && op.Positions[1] == 1:
+		return parser.Operation{Opcode: 'D', Positions: []int{op.Positions[0]}}
+	case op.Opcode == 'i' && op.Positions[0] == 0:
+		return parser.Operation{Opcode: '^', Chars: op.Chars}
+	case op.Opcode == 'x' && op.Positions[0] == 0:
+		return parser.Operation{Opcode: '\'', Positions: []int{op.Positions[1]}}
+	}
+	return op
+}
+
+// rewritePair replaces two adjacent operations with a single equivalent
+func rewritePair(a parser.Operation, b parser.Operation) (parser.Operation, bool) {
+	switch {
+	case a.Opcode == '\'' && b.Opcode == '\'':
+		return parser.Operation{Opcode: '\'', Positions: []int{min(a.Positions[0], b.Positions[0])}}, true
+	case a.Opcode == 'l' && b.Opcode == 'T' && b.Positions[0] == 0:
+		return parser.Operation{Opcode: 'c'}, true
+	case a.Opcode == 'u' && b.Opcode == 'T' && b.Positions[0] == 0:
+		return parser.Operation{Opcode: 'C'}, true
+	}
+	return parser.Operation{}, false
+}
+
+// mirror returns the operation that has the same effect on a reversed word
+func mirror(op parser.Operation) (parser.Operation, bool) {
+	switch op.Opcode {
+	case '$':
+		return parser.Operation{Opcode: '^', Chars: op.Chars}, true
+	case '^':
+		return parser.Operation{Opcode: '$', Chars: op.Chars}, true
+	case '[':
+		return parser.Operation{Opcode: ']'}, true
+	case ']':
+		return parser.Operation{Opcode: '['}, true
+	}
+	return parser.Operation{}, false
+}
+
+// OptimizeRules prints the optimized form of each rule from stdin
+//
+// # Rules that cannot be parsed are printed unchanged
+//
+// Args:
+//
+//	stdIn (*bufio.Scanner): Rule lines as a buffer
+//	w (io.Writer): Destination for the output
+//
+// Returns:
+//
+//	None
+func OptimizeRules(stdIn *bufio.Scanner, w io.Writer) {
+	for stdIn.Scan() {
+		optimized, err := Optimize(stdIn.Text())
+		if err != nil {
+			fmt.Fprintln(w, stdIn.Text())
+			continue
+		}
+		fmt.Fprintln(w, optimized)
+	}
+}
+
+// isNoop checks if an operation never changes a word
+func isNoop(op parser.Operation) bool {
+	switch op.Opcode {
+	case ':':
+		return true
+	case 'p', 'z', 'Z', 'y', 'Y':
+		return op.Positions[0] == 0
+	case '*':
+		return op.Positions[0] == op.Positions[1]
+	case 's':
+		return op.Chars[0] == op.Chars[1]
+	}
+	return false
+}
+
+// same checks if two operations are identical
+func same(a parser.Operation, b parser.Operation) bool {
+	return a.Opcode == b.Opcode && string(a.Chars) == string(b.Chars) && equalInts(a.Positions, b.Positions)
+}
+
+// equalInts checks if two int slices hold the same values
+func equalInts(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// format serializes operations and uses ":" for an empty rule
+func format(ops []parser.Operation) string {
+	if len(ops) == 0 {
+		return ":"
+	}
+	return parser.Format(ops)
+}
@@ -0,0 +1,98 @@
+package optimize
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jakewnuk/rulecat/pkg/engine"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"$1 $2", "$1 $2"},
+		{"$1$2:", "$1 $2"},
+		{"T0 T0 $a", "$a"},
+		{"T0 T1 T1 T0", ":"},
+		{"r r", ":"},
+		{"t l $1", "l $1"},
+		{"l l", "l"},
+		{"$a ]", "$a ]"},
+		{"^a [ $b", "^a [ $b"},
+		{"[ [ [", "[ [ ["},
+		{"D3 D3", "D3 D3"},
+		{"O12 D1", "O12 D1"},
+		{"O02 [", "O02 ["},
+		{"{ } p0 sxx", ":"},
+		{"sa@ sa@", "sa@"},
+	}
+
+	for _, test := range tests {
+		got, err := Normalize(test.rule)
+		if err != nil {
+			t.Errorf("Normalize(%q) returned error %v", test.rule, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Normalize(%q) = %q; want %q", test.rule, got, test.want)
+		}
+		checkEquivalent(t, "Normalize", test.rule, got)
+	}
+}
+
+// probes are words of every length up to past the positions used in tests
+// and a word at the longest length the engine changes
+var probes = []string{"", "a", "ab", "abc", "abcd", "PassWord", "PassWord12345678", strings.Repeat("Pass", engine.MaxWordLength/4)}
+
+// checkEquivalent reports probe words that two rules transform differently
+func checkEquivalent(t *testing.T, name string, rule string, got string) {
+	t.Helper()
+	for _, probe := range probes {
+		before, errBefore := engine.Apply(probe, rule)
+		after, errAfter := engine.Apply(probe, got)
+		if before != after || (errBefore == nil) != (errAfter == nil) {
+			t.Errorf("%s(%q) = %q changes %q from %q to %q", name, rule, got, probe, before, after)
+		}
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"$a $b", "$a $b"},
+		{"T0 T0 $a", "$a"},
+		{"] ] ] ] $a $b $c $d", "] ] ] ] $a $b $c $d"},
+		{"] ] ] $a $b $c", "] ] ] $a $b $c"},
+		{"[ [ '4", "[ [ '4"},
+		{"O02 '4", "O02 '4"},
+		{"[ [", "[ ["},
+		{"D0 D0 D0", "[ [ ["},
+		{"O31", "D3"},
+		{"i0a", "^a"},
+		{"x04", "'4"},
+		{"x02 D1", "'2 D1"},
+		{"'6 '4", "'4"},
+		{"l T0", "c"},
+		{"r $a r", "^a"},
+		{"r [ r", "]"},
+	}
+
+	for _, test := range tests {
+		got, err := Optimize(test.rule)
+		if err != nil {
+			t.Errorf("Optimize(%q) returned error %v", test.rule, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Optimize(%q) = %q; want %q", test.rule, got, test.want)
+		}
+		if len(got) > len(test.rule) {
+			t.Errorf("Optimize(%q) = %q is longer than the rule", test.rule, got)
+		}
+		checkEquivalent(t, "Optimize", test.rule, got)
+	}
+}
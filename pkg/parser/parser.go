@@ -0,0 +1,245 @@
+// Package parser contains the logic for parsing Hashcat rules into operations
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// signatures maps each rule function to its arguments where 'N' is a
+// position and 'C' is a character
+var signatures = map[byte]string{
+	':': "", 'l': "", 'u': "", 'c': "", 'C': "", 't': "", 'r': "", 'd': "",
+	'f': "", '{': "", '}': "", '[': "", ']': "", 'q': "", 'k': "", 'K': "",
+	'E': "", 'M': "", '4': "", '6': "", 'Q': "",
+	'T': "N", 'p': "N", 'D': "N", 'z': "N", 'Z': "N", '\'': "N", 'y': "N",
+	'Y': "N", 'L': "N", 'R': "N", '+': "N", '-': "N", '.': "N", ',': "N",
+	'<': "N", '>': "N", '_': "N",
+	'$': "C", '^': "C", '@': "C", '!': "C", '/': "C", '(': "C", ')': "C",
+	'e': "C",
+	'i': "NC", 'o': "NC", '=': "NC", '%': "NC", '3': "NC",
+	'x': "NN", 'O': "NN", '*': "NN",
+	's': "CC",
+	'X': "NNN",
+}
+
+// MaxPosition is the largest value a position argument can hold
+const MaxPosition = 35
+
+// Operation is a single rule function and its arguments
+type Operation struct {
+	// Opcode is the function character such as '$' or 's'
+	Opcode byte
+	// Positions are the positional arguments in order
+	Positions []int
+	// Chars are the character arguments in order
+	Chars []byte
+	// Column is the 1-based column of the opcode in the source line
+	Column int
+}
+
+// Error is a parse error for a rule line
+type Error struct {
+	// Column is the 1-based column where the error was found
+	Column int
+	// Opcode is the function being parsed or the unknown character
+	Opcode byte
+	// Reason describes the problem
+	Reason string
+}
+
+// Error formats the parse error with its column
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %q: %s", e.Column, e.Opcode, e.Reason)
+}
+
+// Signature returns the argument kinds for a rule function
+//
+// Args:
+//
+//	op (byte): Function character
+//
+// Returns:
+//
+//	(string): Argument kinds where 'N' is a position and 'C' is a character
+//	(bool): If the function is known
+func Signature(op byte) (string, bool) {
+	sig, ok := signatures[op]
+	return sig, ok
+}
+
+// Parse parses a rule line into operations
+//
+// # Spaces and tabs between functions are ignored
+//
+// Args:
+//
+//	rule (string): Rule line to parse
+//
+// Returns:
+//
+//	([]Operation): Parsed operations
+//	(error): *Error if the rule is malformed
+func Parse(rule string) ([]Operation, error) {
+	var ops []Operation
+	i := 0
+	for i < len(rule) {
+		op := rule[i]
+		if op == ' ' || op == '\t' {
+			i++
+			continue
+		}
+		sig, ok := signatures[op]
+		if !ok {
+			return nil, &Error{Column: i + 1, Opcode: op, Reason: "unknown function"}
+		}
+
+		o := Operation{Opcode: op, Column: i + 1}
+		i++
+		for _, kind := range []byte(sig) {
+			if i >= len(rule) {
+				return nil, &Error{Column: i + 1, Opcode: op, Reason: "missing argument"}
+			}
+			if kind == 'N' {
+				p, ok := PositionValue(rule[i])
+				if !ok {
+					return nil, &Error{Column: i + 1, Opcode: op, Reason: fmt.Sprintf("invalid position %q, must be 0-9 or A-Z", rule[i])}
+				}
+				o.Positions = append(o.Positions, p)
+				i++
+				continue
+			}
+			c, width := charValue(rule[i:])
+			o.Chars = append(o.Chars, c)
+			i += width
+		}
+		ops = append(ops, o)
+	}
+	return ops, nil
+}
+
+// Format serializes operations into a canonical rule line
+//
+// # Functions are separated by a single space and non-printable characters
+// are written in \xNN format
+//
+// Args:
+//
+//	ops ([]Operation): Operations to serialize
+//
+// Returns:
+//
+//	(string): Rule line
+func Format(ops []Operation) string {
+	parts := make([]string, 0, len(ops))
+	for _, o := range ops {
+		parts = append(parts, o.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// String serializes a single operation in canonical form
+func (o Operation) String() string {
+	var b strings.Builder
+	b.WriteByte(o.Opcode)
+	p, c := 0, 0
+	for _, kind := range []byte(signatures[o.Opcode]) {
+		if kind == 'N' {
+			if p < len(o.Positions) {
+				b.WriteByte(PositionChar(o.Positions[p]))
+			}
+			p++
+			continue
+		}
+		if c < len(o.Chars) {
+			b.WriteString(charString(o.Chars[c]))
+		}
+		c++
+	}
+	return b.String()
+}
+
+// PositionValue converts a position argument to an integer
+//
+// Args:
+//
+//	b (byte): Position character (0-9 or A-Z)
+//
+// Returns:
+//
+//	(int): Position value
+//	(bool): If the character was a valid position
+func PositionValue(b byte) (int, bool) {
+	switch {
+	case b >= '0' && b <= '9':
+		return int(b - '0'), true
+	case b >= 'A' && b <= 'Z':
+		return int(b-'A') + 10, true
+	}
+	return 0, false
+}
+
+// PositionChar converts an integer to a position argument
+//
+// # Values outside of 0-35 are clamped to the nearest valid position
+//
+// Args:
+//
+//	n (int): Position value
+//
+// Returns:
+//
+//	(byte): Position character (0-9 or A-Z)
+func PositionChar(n int) byte {
+	switch {
+	case n < 0:
+		return '0'
+	case n < 10:
+		return byte('0' + n)
+	case n <= MaxPosition:
+		return byte('A' + n - 10)
+	}
+	return 'Z'
+}
+
+// charValue reads a character argument that may be a \xNN hex escape
+//
+// Args:
+//
+//	s (string): Remaining rule text starting at the argument
+//
+// Returns:
+//
+//	(byte): Character value
+//	(int): Number of bytes consumed
+func charValue(s string) (byte, int) {
+	if len(s) >= 4 && s[0] == '\\' && s[1] == 'x' {
+		hi, okHi := hexValue(s[2])
+		lo, okLo := hexValue(s[3])
+		if okHi && okLo {
+			return hi<<4 | lo, 4
+		}
+	}
+	return s[0], 1
+}
+
+// charString writes a character argument escaping non-printable bytes
+func charString(c byte) string {
+	if c < 0x20 || c > 0x7e {
+		return fmt.Sprintf("\\x%02X", c)
+	}
+	return string(c)
+}
+
+// hexValue converts a hex digit to its value
+func hexValue(b byte) (byte, bool) {
+	switch {
+	case b >= '0' && b <= '9':
+		return b - '0', true
+	case b >= 'a' && b <= 'f':
+		return b - 'a' + 10, true
+	case b >= 'A' && b <= 'F':
+		return b - 'A' + 10, true
+	}
+	return 0, false
+}
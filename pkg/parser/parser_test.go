@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		rule string
+		want []Operation
+	}{
+		{"$1", []Operation{{Opcode: '$', Chars: []byte{'1'}, Column: 1}}},
+		{"T0 TA", []Operation{
+			{Opcode: 'T', Positions: []int{0}, Column: 1},
+			{Opcode: 'T', Positions: []int{10}, Column: 4},
+		}},
+		{"sa@i5!", []Operation{
+			{Opcode: 's', Chars: []byte{'a', '@'}, Column: 1},
+			{Opcode: 'i', Positions: []int{5}, Chars: []byte{'!'}, Column: 4},
+		}},
+		{"$\\xE4 x12", []Operation{
+			{Opcode: '$', Chars: []byte{0xE4}, Column: 1},
+			{Opcode: 'x', Positions: []int{1, 2}, Column: 7},
+		}},
+		{"$ ", []Operation{{Opcode: '$', Chars: []byte{' '}, Column: 1}}},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		got, err := Parse(test.rule)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error %v", test.rule, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Parse(%q) = %+v; want %+v", test.rule, got, test.want)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		rule   string
+		column int
+		opcode byte
+	}{
+		{"$1 w", 4, 'w'},
+		{"$", 2, '$'},
+		{"$1 Tz", 5, 'T'},
+		{"x1", 3, 'x'},
+		{"#", 1, '#'},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.rule)
+		var perr *Error
+		if !errors.As(err, &perr) {
+			t.Errorf("Parse(%q) error = %v; want *Error", test.rule, err)
+			continue
+		}
+		if perr.Column != test.column || perr.Opcode != test.opcode {
+			t.Errorf("Parse(%q) error at column %d for %q; want column %d for %q", test.rule, perr.Column, perr.Opcode, test.column, test.opcode)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"$1$2$3", "$1 $2 $3"},
+		{"  T0   TA ", "T0 TA"},
+		{"sa@i5!", "sa@ i5!"},
+		{"$\\xe4^\\x0a", "$\\xE4 ^\\x0A"},
+		{"$ $a", "$  $a"},
+		{"X123:", "X123 :"},
+	}
+
+	for _, test := range tests {
+		ops, err := Parse(test.rule)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error %v", test.rule, err)
+			continue
+		}
+		got := Format(ops)
+		if got != test.want {
+			t.Errorf("Format(Parse(%q)) = %q; want %q", test.rule, got, test.want)
+		}
+	}
+}
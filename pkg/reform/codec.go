@@ -0,0 +1,534 @@
+package reform
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime/quotedprintable"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/jakewnuk/rulecat/pkg/utils"
+	"golang.org/x/net/idna"
+)
+
+// Codec is a named text encoding
+type Codec struct {
+	// Name is used to select the codec such as "base64"
+	Name string
+	// Description is shown in the encode and decode help
+	Description string
+	// Encode converts text into the encoding
+	Encode func(s string) string
+	// Decode converts text in the encoding back or returns an error if the
+	// text is not valid in the encoding
+	Decode func(s string) (string, error)
+	// Detect reports if text looks like the encoding or is nil if the codec
+	// is not detected automatically
+	Detect func(s string) bool
+}
+
+// codecs are the registered codecs in the order they were added
+var codecs []Codec
+
+// DefaultCodecs are the codecs used when none are selected
+var DefaultCodecs = []string{"url", "html", "escape"}
+
+// Patterns that detect encoded text
+var (
+	urlPattern    = regexp.MustCompile(`%[0-9A-Fa-f]{2}`)
+	htmlPattern   = regexp.MustCompile(`&(#[0-9]+|#[xX][0-9A-Fa-f]+|[A-Za-z][A-Za-z0-9]*);`)
+	escapePattern = regexp.MustCompile(`\\u[0-9A-Fa-f]{4}`)
+	base64Pattern = regexp.MustCompile(`^[A-Za-z0-9+/]+={0,2}$`)
+	hexPattern    = regexp.MustCompile(`^(?:[0-9A-Fa-f]{2})+$`)
+	hexLetter     = regexp.MustCompile(`[A-Fa-f]`)
+)
+
+func init() {
+	for _, c := range []Codec{
+		{Name: "url", Description: "URL query encoding", Encode: url.QueryEscape, Decode: url.QueryUnescape, Detect: urlPattern.MatchString},
+		{Name: "html", Description: "HTML entity encoding", Encode: html.EscapeString, Decode: decodeHTML, Detect: htmlPattern.MatchString},
+		{Name: "escape", Description: "Unicode \\uXXXX escapes", Encode: AsciiEscapeUnicode, Decode: func(s string) (string, error) { return UnescapeUnicode(s), nil }, Detect: escapePattern.MatchString},
+		{Name: "base64", Description: "Standard base64", Encode: encodeBase64(base64.StdEncoding), Decode: decodeBase64(base64.StdEncoding, base64.RawStdEncoding), Detect: detectBase64},
+		{Name: "base64url", Description: "URL-safe base64", Encode: encodeBase64(base64.URLEncoding), Decode: decodeBase64(base64.URLEncoding, base64.RawURLEncoding)},
+		{Name: "hex", Description: "Lowercase hex", Encode: func(s string) string { return hex.EncodeToString([]byte(s)) }, Decode: decodeHex, Detect: detectHex},
+		{Name: "hashcat-hex", Description: "Hashcat $HEX[...] format", Encode: utils.EncodeHexString, Decode: decodeHashcatHex},
+		{Name: "rot13", Description: "ROT13 letter rotation", Encode: Rot13, Decode: func(s string) (string, error) { return Rot13(s), nil }},
+		{Name: "reverse", Description: "Reversed text", Encode: utils.ReverseString, Decode: func(s string) (string, error) { return utils.ReverseString(s), nil }},
+		{Name: "qp", Description: "Quoted-printable", Encode: QuotedPrintable, Decode: decodeQuotedPrintable},
+		{Name: "punycode", Description: "Punycode IDNA labels", Encode: Punycode, Decode: idna.Punycode.ToUnicode},
+		{Name: "json", Description: "JSON string escapes", Encode: JSONEscape, Decode: decodeJSON},
+		{Name: "url2", Description: "Double URL encoding", Encode: func(s string) string { return url.QueryEscape(url.QueryEscape(s)) }, Decode: decodeURL2},
+		{Name: "c", Description: "C string octal byte escapes", Encode: CEscape, Decode: Unescape},
+		{Name: "go", Description: "Go string hex byte escapes", Encode: GoEscape, Decode: Unescape},
+		{Name: "python", Description: "Python bytes hex escapes", Encode: PythonEscape, Decode: Unescape},
+	} {
+		if err := Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register adds a codec that can be selected by name
+//
+// Args:
+//
+//	c (Codec): Codec to add
+//
+// Returns:
+//
+//	(error): Error if the name is empty or already registered
+func Register(c Codec) error {
+	if c.Name == "" || c.Encode == nil || c.Decode == nil {
+		return fmt.Errorf("codec needs a name and encode and decode functions")
+	}
+	if _, ok := Lookup(c.Name); ok {
+		return fmt.Errorf("codec %q is already registered", c.Name)
+	}
+	codecs = append(codecs, c)
+	return nil
+}
+
+// Lookup finds a registered codec by name
+//
+// Args:
+//
+//	name (string): Name of the codec
+//
+// Returns:
+//
+//	(Codec): Codec with the name
+//	(bool): If the codec was found
+func Lookup(name string) (Codec, bool) {
+	for _, c := range codecs {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Codec{}, false
+}
+
+// Codecs returns every registered codec in the order they were added
+func Codecs() []Codec {
+	return append([]Codec(nil), codecs...)
+}
+
+// Names returns the names of every registered codec
+func Names() []string {
+	names := make([]string, len(codecs))
+	for i, c := range codecs {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Select finds codecs from a comma separated list of names
+//
+// # An empty list selects DefaultCodecs
+//
+// Args:
+//
+//	list (string): Names such as "url,base64,hex"
+//
+// Returns:
+//
+//	([]Codec): Codecs in the order of the list
+//	(error): Error if a name is not registered
+func Select(list string) ([]Codec, error) {
+	names := DefaultCodecs
+	if list != "" {
+		names = strings.Split(list, ",")
+	}
+
+	var selected []Codec
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		c, ok := Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown codec %q (%s)", name, strings.Join(Names(), ", "))
+		}
+		selected = append(selected, c)
+	}
+	return selected, nil
+}
+
+// EncodeWith encodes a string with each codec
+//
+// # Only encodings that are different than the input string are returned
+//
+// Args:
+//
+//	s (string): Input string
+//	codecs ([]Codec): Codecs to encode with
+//
+// Returns:
+//
+//	([]string): Encoded strings in codec order
+func EncodeWith(s string, codecs []Codec) []string {
+	var encoded []string
+	for _, c := range codecs {
+		if e := c.Encode(s); e != s {
+			encoded = append(encoded, e)
+		}
+	}
+	return encoded
+}
+
+// Decode decodes a string with the first codec that can decode it
+//
+// # Without codecs the codecs that detect the string are tried in the order
+// they were registered. A codec that returns the string unchanged did not
+// decode it
+//
+// Args:
+//
+//	s (string): Input string
+//	codecs ([]Codec): Codecs to try or nil to detect the encoding
+//
+// Returns:
+//
+//	(string): Decoded string or the input string if no codec decoded it
+//	(bool): If a codec decoded the string
+func Decode(s string, codecs []Codec) (string, bool) {
+	detect := codecs == nil
+	if detect {
+		codecs = Codecs()
+	}
+	for _, c := range codecs {
+		if detect && (c.Detect == nil || !c.Detect(s)) {
+			continue
+		}
+		if decoded, err := c.Decode(s); err == nil && decoded != s {
+			return decoded, true
+		}
+	}
+	return s, false
+}
+
+// Rot13 rotates ASCII letters by 13 places
+//
+// Args:
+//
+//	s (string): Input string
+//
+// Returns:
+//
+//	(string): Rotated string
+func Rot13(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		switch {
+		case c >= 'a' && c <= 'z':
+			b[i] = 'a' + (c-'a'+13)%26
+		case c >= 'A' && c <= 'Z':
+			b[i] = 'A' + (c-'A'+13)%26
+		}
+	}
+	return string(b)
+}
+
+// QuotedPrintable encodes a string as quoted-printable on a single line
+//
+// # Unlike mime/quotedprintable no soft line breaks are added
+//
+// Args:
+//
+//	s (string): Input string
+//
+// Returns:
+//
+//	(string): Encoded string
+func QuotedPrintable(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case (c == ' ' || c == '\t') && i < len(s)-1:
+			b.WriteByte(c)
+		case c >= 33 && c <= 126 && c != '=':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "=%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// Punycode encodes each dot separated label that is not ASCII as an IDNA
+// xn-- label
+//
+// Args:
+//
+//	s (string): Input string
+//
+// Returns:
+//
+//	(string): Encoded string or the input string if it cannot be encoded
+func Punycode(s string) string {
+	encoded, err := idna.Punycode.ToASCII(s)
+	if err != nil {
+		return s
+	}
+	return encoded
+}
+
+// JSONEscape escapes a string for use inside a JSON string
+//
+// Args:
+//
+//	s (string): Input string
+//
+// Returns:
+//
+//	(string): Escaped string without the surrounding quotes
+func JSONEscape(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return s
+	}
+	out := bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+	return string(out[1 : len(out)-1])
+}
+
+// CEscape escapes a string for a C string literal
+//
+// # Bytes outside printable ASCII are written as three digit octal escapes
+// as hex escapes in C would read following hex digits
+//
+// Args:
+//
+//	s (string): Input string
+//
+// Returns:
+//
+//	(string): Escaped string without the surrounding quotes
+func CEscape(s string) string {
+	return byteEscape(s, `"`, func(c byte) string { return fmt.Sprintf("\\%03o", c) })
+}
+
+// GoEscape escapes a string for a Go string literal with byte escapes
+//
+// Args:
+//
+//	s (string): Input string
+//
+// Returns:
+//
+//	(string): Escaped string without the surrounding quotes
+func GoEscape(s string) string {
+	return byteEscape(s, `"`, func(c byte) string { return fmt.Sprintf("\\x%02x", c) })
+}
+
+// PythonEscape escapes a string for a Python bytes literal
+//
+// Args:
+//
+//	s (string): Input string
+//
+// Returns:
+//
+//	(string): Escaped string without the surrounding quotes
+func PythonEscape(s string) string {
+	return byteEscape(s, `'`, func(c byte) string { return fmt.Sprintf("\\x%02x", c) })
+}
+
+// byteEscape escapes backslashes, a quote, common control characters, and
+// bytes outside printable ASCII
+func byteEscape(s string, quote string, escape func(c byte) string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' || string(c) == quote:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c < 32 || c > 126:
+			b.WriteString(escape(c))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// UnescapeUnicode decodes \\uXXXX escapes including surrogate pairs
+//
+// Args:
+//
+//	s (string): Input string
+//
+// Returns:
+//
+//	(string): Decoded string
+func UnescapeUnicode(s string) string {
+	var units []uint16
+	var b strings.Builder
+	flush := func() {
+		b.WriteString(string(utf16.Decode(units)))
+		units = units[:0]
+	}
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], `\u`) && i+6 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+2:i+6], 16, 16); err == nil {
+				units = append(units, uint16(n))
+				i += 6
+				continue
+			}
+		}
+		flush()
+		b.WriteByte(s[i])
+		i++
+	}
+	flush()
+	return b.String()
+}
+
+// Unescape decodes C, Go, and Python backslash escapes
+//
+// # \\xNN hex, \\NNN octal, \\n, \\r, \\t, and escaped quotes and
+// backslashes are supported
+//
+// Args:
+//
+//	s (string): Input string
+//
+// Returns:
+//
+//	(string): Decoded string
+//	(error): Error if an escape is not valid
+func Unescape(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 >= len(s) {
+			return s, fmt.Errorf("trailing backslash")
+		}
+		i++
+		switch c := s[i]; {
+		case c == 'n':
+			b.WriteByte('\n')
+		case c == 'r':
+			b.WriteByte('\r')
+		case c == 't':
+			b.WriteByte('\t')
+		case c == '\\' || c == '"' || c == '\'':
+			b.WriteByte(c)
+		case c == 'x' && i+2 < len(s):
+			n, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return s, fmt.Errorf("invalid hex escape %q", s[i-1:i+3])
+			}
+			b.WriteByte(byte(n))
+			i += 2
+		case c >= '0' && c <= '7' && i+2 < len(s):
+			n, err := strconv.ParseUint(s[i:i+3], 8, 8)
+			if err != nil {
+				return s, fmt.Errorf("invalid octal escape %q", s[i-1:i+3])
+			}
+			b.WriteByte(byte(n))
+			i += 2
+		default:
+			return s, fmt.Errorf("invalid escape %q", s[i-1:i+1])
+		}
+	}
+	return b.String(), nil
+}
+
+// encodeBase64 creates a base64 encode function
+func encodeBase64(enc *base64.Encoding) func(s string) string {
+	return func(s string) string { return enc.EncodeToString([]byte(s)) }
+}
+
+// decodeBase64 creates a base64 decode function that also accepts text
+// without padding
+func decodeBase64(padded, raw *base64.Encoding) func(s string) (string, error) {
+	return func(s string) (string, error) {
+		decoded, err := padded.DecodeString(s)
+		if err != nil {
+			decoded, err = raw.DecodeString(s)
+		}
+		return string(decoded), err
+	}
+}
+
+// detectBase64 reports if text is padded base64 of printable text
+//
+// # Short text is not detected as many words are valid base64
+func detectBase64(s string) bool {
+	if len(s) < 8 || len(s)%4 != 0 || !base64Pattern.MatchString(s) {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	return err == nil && utils.CheckPrintableString(string(decoded))
+}
+
+// decodeHex decodes hex text
+func decodeHex(s string) (string, error) {
+	decoded, err := hex.DecodeString(s)
+	return string(decoded), err
+}
+
+// detectHex reports if text is hex of printable text
+//
+// # Hex of only digits is not detected as it looks like a number
+func detectHex(s string) bool {
+	if len(s) < 8 || !hexPattern.MatchString(s) || !hexLetter.MatchString(s) {
+		return false
+	}
+	decoded, err := hex.DecodeString(s)
+	return err == nil && utils.CheckPrintableString(string(decoded))
+}
+
+// decodeHTML decodes HTML entities
+func decodeHTML(s string) (string, error) {
+	return html.UnescapeString(s), nil
+}
+
+// decodeHashcatHex decodes the Hashcat $HEX[...] format
+func decodeHashcatHex(s string) (string, error) {
+	decoded, ok := utils.DecodeHexString(s)
+	if !ok {
+		return s, fmt.Errorf("not in $HEX[...] format")
+	}
+	return decoded, nil
+}
+
+// decodeQuotedPrintable decodes quoted-printable text
+func decodeQuotedPrintable(s string) (string, error) {
+	decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(s)))
+	return string(decoded), err
+}
+
+// decodeJSON decodes JSON string escapes
+func decodeJSON(s string) (string, error) {
+	var decoded string
+	err := json.Unmarshal([]byte(`"`+s+`"`), &decoded)
+	return decoded, err
+}
+
+// decodeURL2 decodes double URL encoding
+func decodeURL2(s string) (string, error) {
+	once, err := url.QueryUnescape(s)
+	if err != nil {
+		return s, err
+	}
+	return url.QueryUnescape(once)
+}
@@ -1,11 +1,15 @@
 // Package reform controls the logic for reformatting text into hash structures
+// and other encodings through a registry of codecs
 package reform
 
 import (
 	"bufio"
 	"fmt"
 	"html"
+	"io"
 	"net/url"
+	"unicode"
+	"unicode/utf16"
 )
 
 // EncodeInput URL and HTML encode standard input and prints new instances
@@ -13,28 +17,35 @@ import (
 // Args:
 //
 //	stdIn (*bufio.Scanner): Standard in as a buffer
+//	w (io.Writer): Destination for the output
 //
 // Returns:
 //
 //	None
-func EncodeInput(stdIn *bufio.Scanner) {
+func EncodeInput(stdIn *bufio.Scanner, w io.Writer) {
 	for stdIn.Scan() {
-		urlEncoded, htmlEncoded, escapeEncoded := EncodeString(stdIn.Text())
-
-		if urlEncoded != "" {
-			fmt.Println(urlEncoded)
-		}
-
-		if htmlEncoded != "" {
-			fmt.Println(htmlEncoded)
-		}
-
-		if escapeEncoded != "" {
-			fmt.Println(escapeEncoded)
+		for _, encoded := range Encode(stdIn.Text()) {
+			fmt.Fprintln(w, encoded)
 		}
 	}
 }
 
+// Encode URL, HTML, and Unicode escape encodes a string with DefaultCodecs
+//
+// # Only encodings that are different than the input string are returned
+//
+// Args:
+//
+//	s (string): Input string
+//
+// Returns:
+//
+//	([]string): Encoded strings in URL, HTML, and escape order
+func Encode(s string) []string {
+	defaults, _ := Select("")
+	return EncodeWith(s, defaults)
+}
+
 // EncodeString is used to URL and HTML encode a string where possible
 //
 // # Only returns if the output is different than the input string
@@ -83,9 +94,15 @@ func AsciiEscapeUnicode(str string) string {
 
 	for _, r := range runes {
 		if r > 127 {
-			// The rune is non-ASCII
-			escapedRune := []rune(fmt.Sprintf("\\u%04x", r))
-			escapedRunes = append(escapedRunes, escapedRune...)
+			// The rune is non-ASCII and written as a UTF-16 surrogate pair
+			// when it does not fit in four hex digits
+			units := []rune{r}
+			if hi, lo := utf16.EncodeRune(r); hi != unicode.ReplacementChar {
+				units = []rune{hi, lo}
+			}
+			for _, u := range units {
+				escapedRunes = append(escapedRunes, []rune(fmt.Sprintf("\\u%04x", u))...)
+			}
 		} else {
 			escapedRunes = append(escapedRunes, r)
 		}
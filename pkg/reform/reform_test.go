@@ -0,0 +1,128 @@
+package reform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodecs(t *testing.T) {
+	tests := []struct {
+		codec string
+		in    string
+		want  string
+	}{
+		{"url", "a b&c", "a+b%26c"},
+		{"html", "<a>", "&lt;a&gt;"},
+		{"escape", "ä", "\\u00e4"},
+		{"escape", "a😀", "a\\ud83d\\ude00"},
+		{"base64", "pass?>", "cGFzcz8+"},
+		{"base64url", "pass?>", "cGFzcz8-"},
+		{"hex", "pass", "70617373"},
+		{"hashcat-hex", "pass", "$HEX[70617373]"},
+		{"rot13", "Pass123", "Cnff123"},
+		{"reverse", "pässw", "wssäp"},
+		{"qp", "a=b ä ", "a=3Db =C3=A4=20"},
+		{"punycode", "pässword", "xn--pssword-5wa"},
+		{"punycode", "münchen.de", "xn--mnchen-3ya.de"},
+		{"json", "a\"b\\c\t<", "a\\\"b\\\\c\\t<"},
+		{"url2", "a b", "a%2Bb"},
+		{"c", "a\"ä\n", "a\\\"\\303\\244\\n"},
+		{"go", "a\"ä\n", "a\\\"\\xc3\\xa4\\n"},
+		{"python", "a'\"ä", "a\\'\"\\xc3\\xa4"},
+	}
+
+	for _, test := range tests {
+		c, ok := Lookup(test.codec)
+		if !ok {
+			t.Fatalf("Lookup(%q) found no codec", test.codec)
+		}
+		if got := c.Encode(test.in); got != test.want {
+			t.Errorf("%s.Encode(%q) = %q; want %q", test.codec, test.in, got, test.want)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	codecs, err := Select("hex, rot13")
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	got := EncodeWith("abc", codecs)
+	if want := []string{"616263", "nop"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("EncodeWith() = %q; want %q", got, want)
+	}
+
+	if got, want := Encode("a b"), []string{"a+b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Encode() = %q; want %q", got, want)
+	}
+
+	if _, err := Select("url,bogus"); err == nil {
+		t.Errorf("Select() with an unknown codec error = nil; want an error")
+	}
+	if err := Register(Codec{Name: "url", Encode: Rot13}); err == nil {
+		t.Errorf("Register() of a duplicate name error = nil; want an error")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, c := range Codecs() {
+		for _, word := range []string{"pass word", "pässw<&>\"'\\1", "päss😀word"} {
+			got, err := c.Decode(c.Encode(word))
+			if err != nil || got != word {
+				t.Errorf("%s.Decode(%s.Encode(%q)) = %q, %v; want %q", c.Name, c.Name, word, got, err, word)
+			}
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"p%40ss", "p@ss", true},
+		{"p&amp;ss&#33;", "p&ss!", true},
+		{"p\\u00e4ss\\ud83d\\ude00", "päss😀", true},
+		{"cGFzc3dvcmQ=", "password", true},
+		{"70617373776f7264", "password", true},
+		{"password", "password", false},
+		{"20242024", "20242024", false},
+		{"deadbeef", "deadbeef", false},
+		{"AAAAAAAA", "AAAAAAAA", false},
+	}
+
+	for _, test := range tests {
+		got, ok := Decode(test.in, nil)
+		if got != test.want || ok != test.ok {
+			t.Errorf("Decode(%q) = %q, %v; want %q, %v", test.in, got, ok, test.want, test.ok)
+		}
+	}
+
+	codecs, err := Select("base64")
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if got, ok := Decode("cGFzcw", codecs); got != "pass" || !ok {
+		t.Errorf("Decode() with base64 = %q, %v; want %q, true", got, ok, "pass")
+	}
+	if got, ok := Decode("p%40ss", codecs); got != "p%40ss" || ok {
+		t.Errorf("Decode() with base64 = %q, %v; want the input and false", got, ok)
+	}
+
+	// codecs that cannot fail only decode text they change
+	for _, names := range []string{"html", "escape", "html,url,escape"} {
+		if codecs, err = Select(names); err != nil {
+			t.Fatalf("Select(%q) error = %v", names, err)
+		}
+		if got, ok := Decode("password", codecs); got != "password" || ok {
+			t.Errorf("Decode() with %s = %q, %v; want the input and false", names, got, ok)
+		}
+	}
+	if codecs, err = Select("html,url"); err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if got, ok := Decode("p%40ss", codecs); got != "p@ss" || !ok {
+		t.Errorf("Decode() with html,url = %q, %v; want %q, true", got, ok, "p@ss")
+	}
+}
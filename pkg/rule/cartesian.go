@@ -0,0 +1,197 @@
+package rule
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"strings"
+
+	"github.com/jakewnuk/rulecat/pkg/dialect"
+	"github.com/jakewnuk/rulecat/pkg/parser"
+	"github.com/jakewnuk/rulecat/pkg/validate"
+)
+
+// Source opens the rule lines of one operand of a product
+//
+// # A source is opened again for every combination of the operands before
+// it so it must return the same lines each time
+type Source func() (io.ReadCloser, error)
+
+// ProductSize counts the rules a product of sources can create
+//
+// # Each source is read once and empty lines are not counted. The size
+// stops at math.MaxUint64 if it overflows
+//
+// Args:
+//
+//	sources ([]Source): Operands of the product
+//
+// Returns:
+//
+//	([]int): Number of rule lines in each source
+//	(uint64): Number of rules in the product
+//	(error): ErrRead or ErrLineTooLong if a source cannot be read
+func ProductSize(sources []Source) ([]int, uint64, error) {
+	counts := make([]int, len(sources))
+	total := uint64(1)
+	for i, source := range sources {
+		err := scanSource(source, func(line string) error {
+			counts[i]++
+			return nil
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		total = mulSaturate(total, uint64(counts[i]))
+	}
+	return counts, total, nil
+}
+
+// Product writes the Cartesian product of lines of text and rule sources
+//
+// # Each line of text is followed by one line of every source in order.
+// Empty lines of the text and the sources are skipped as in ProductSize.
+// Sources are streamed from disk for every combination so memory does not
+// grow with their size. Combinations with more than validate.MaxFunctions
+// functions are skipped and reported to stderr
+//
+// Args:
+//
+//	r (io.Reader): Lines of text to place first
+//	w (io.Writer): Destination for the rules
+//	sources ([]Source): Rule lines to place after the text in order
+//	d (dialect.Dialect): Syntax to write rules in
+//
+// Returns:
+//
+//	(error): ErrRead or ErrLineTooLong if reading fails and ErrWrite if
+//	writing fails
+func Product(r io.Reader, w io.Writer, sources []Source, d dialect.Dialect) error {
+	counts, _, err := ProductSize(sources)
+	if err != nil {
+		return err
+	}
+
+	p := &product{w: w, sources: sources, d: d, remaining: make([]uint64, len(sources)+1)}
+	p.remaining[len(sources)] = 1
+	for i := len(sources) - 1; i >= 0; i-- {
+		p.remaining[i] = mulSaturate(p.remaining[i+1], uint64(counts[i]))
+	}
+	if header := d.Header(); header != "" {
+		if _, err := fmt.Fprintln(w, header); err != nil {
+			return WriteError(err)
+		}
+	}
+
+	err = scanLines(r, func(text string) error {
+		return p.extend(0, text, functionCount(text))
+	})
+	if err != nil {
+		return err
+	}
+
+	if p.skipped > 0 {
+		fmt.Fprintf(os.Stderr, "WARNING: skipped %d rules with more than %d functions\n", p.skipped, validate.MaxFunctions)
+	}
+	return nil
+}
+
+// product is the state of a Cartesian product being written
+type product struct {
+	w       io.Writer
+	sources []Source
+	d       dialect.Dialect
+	// remaining is the number of rules each source depth creates per prefix
+	remaining []uint64
+	// skipped is the number of rules over the function limit
+	skipped uint64
+}
+
+// extend writes every rule that starts with a prefix
+//
+// Args:
+//
+//	depth (int): Index of the next source to add
+//	prefix (string): Rule created from the earlier operands
+//	functions (int): Number of functions in the prefix
+//
+// Returns:
+//
+//	(error): Error from reading a source or writing a rule
+func (p *product) extend(depth int, prefix string, functions int) error {
+	if functions > validate.MaxFunctions {
+		p.skipped += p.remaining[depth]
+		return nil
+	}
+	if depth == len(p.sources) {
+		return p.write(prefix)
+	}
+	return scanSource(p.sources[depth], func(line string) error {
+		return p.extend(depth+1, prefix+" "+line, functions+functionCount(line))
+	})
+}
+
+// write renders a rule and writes it
+func (p *product) write(rule string) error {
+	output, err := p.d.Render(rule)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: skipping %q: %s\n", rule, err)
+		return nil
+	}
+	if _, err := fmt.Fprintln(p.w, output); err != nil {
+		return WriteError(err)
+	}
+	return nil
+}
+
+// scanSource calls a function for each non-empty line of a source
+func scanSource(source Source, fn func(line string) error) error {
+	rc, err := source()
+	if err != nil {
+		return ReadError(err, 0)
+	}
+	defer rc.Close()
+	return scanLines(rc, fn)
+}
+
+// scanLines calls a function for each non-empty line of a reader
+func scanLines(r io.Reader, fn func(line string) error) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if text == "" {
+			continue
+		}
+		if err := fn(text); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ReadError(err, line+1)
+	}
+	return nil
+}
+
+// functionCount returns the number of functions in a rule or the number of
+// fields if the rule cannot be parsed
+func functionCount(rule string) int {
+	ops, err := parser.Parse(rule)
+	if err != nil {
+		return len(strings.Fields(rule))
+	}
+	return len(ops)
+}
+
+// mulSaturate multiplies two sizes and stops at math.MaxUint64
+func mulSaturate(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	if hi != 0 {
+		return math.MaxUint64
+	}
+	return lo
+}
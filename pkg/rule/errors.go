@@ -0,0 +1,80 @@
+package rule
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jakewnuk/rulecat/pkg/parser"
+)
+
+// MaxLineLength is the longest input line in bytes that can be read
+const MaxLineLength = bufio.MaxScanTokenSize
+
+// Errors returned by the streaming functions
+var (
+	// ErrInvalidArgument is returned when a mode argument is not valid
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrRead is returned when input cannot be read
+	ErrRead = errors.New("read failed")
+	// ErrLineTooLong is returned when an input line is longer than
+	// MaxLineLength
+	ErrLineTooLong = errors.New("line too long")
+	// ErrWrite is returned when output cannot be written
+	ErrWrite = errors.New("write failed")
+)
+
+// ReadError wraps an error from reading input
+//
+// Args:
+//
+//	err (error): Error from the reader or scanner
+//	line (int): Line number being read or zero if unknown
+//
+// Returns:
+//
+//	(error): ErrLineTooLong or ErrRead wrapping the original error
+func ReadError(err error, line int) error {
+	where := ""
+	if line > 0 {
+		where = fmt.Sprintf("line %d: ", line)
+	}
+	if errors.Is(err, bufio.ErrTooLong) {
+		return fmt.Errorf("%w: %slonger than %d bytes", ErrLineTooLong, where, MaxLineLength)
+	}
+	return fmt.Errorf("%w: %s%w", ErrRead, where, err)
+}
+
+// WriteError wraps an error from writing output
+//
+// # Writes to a closed pipe return an error wrapping syscall.EPIPE
+//
+// Args:
+//
+//	err (error): Error from the writer
+//
+// Returns:
+//
+//	(error): ErrWrite wrapping the original error
+func WriteError(err error) error {
+	return fmt.Errorf("%w: %w", ErrWrite, err)
+}
+
+// checkIndex checks that a start index is a valid rule position
+func checkIndex(index int) error {
+	if index < 0 || index > parser.MaxPosition {
+		return fmt.Errorf("%w: start index %d must be 0-%d", ErrInvalidArgument, index, parser.MaxPosition)
+	}
+	return nil
+}
+
+// checkMode checks that a mode is one of the allowed values
+func checkMode(name string, mode string, allowed ...string) error {
+	for _, a := range allowed {
+		if mode == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s mode %q must be one of %s", ErrInvalidArgument, name, mode, strings.Join(allowed, ", "))
+}
@@ -0,0 +1,154 @@
+package rule
+
+import "fmt"
+
+// AppendGenerator creates a generator for append rules
+//
+// Args:
+//
+//	mode (string): Mode function to use to modify operation
+//
+// Returns:
+//
+//	(Generator): Generator that calls Append
+//	(error): ErrInvalidArgument if the mode is not valid
+func AppendGenerator(mode string) (Generator, error) {
+	if mode != "" {
+		if err := checkMode("append", mode, "default", "remove", "shift"); err != nil {
+			return nil, err
+		}
+	}
+	return func(word string) []string { return Append(word, mode) }, nil
+}
+
+// PrependGenerator creates a generator for prepend rules
+//
+// Args:
+//
+//	mode (string): Mode function to use to modify operation
+//
+// Returns:
+//
+//	(Generator): Generator that calls Prepend
+//	(error): ErrInvalidArgument if the mode is not valid
+func PrependGenerator(mode string) (Generator, error) {
+	if mode != "" {
+		if err := checkMode("prepend", mode, "default", "remove", "shift"); err != nil {
+			return nil, err
+		}
+	}
+	return func(word string) []string { return Prepend(word, mode) }, nil
+}
+
+// InsertGenerator creates a generator for insert rules
+//
+// Args:
+//
+//	index (int): Position of the first character
+//
+// Returns:
+//
+//	(Generator): Generator that calls Insert
+//	(error): ErrInvalidArgument if the index is not valid
+func InsertGenerator(index int) (Generator, error) {
+	if err := checkIndex(index); err != nil {
+		return nil, err
+	}
+	return func(word string) []string { return Insert(word, index) }, nil
+}
+
+// OverwriteGenerator creates a generator for overwrite rules
+//
+// Args:
+//
+//	index (int): Position of the first character
+//
+// Returns:
+//
+//	(Generator): Generator that calls Overwrite
+//	(error): ErrInvalidArgument if the index is not valid
+func OverwriteGenerator(index int) (Generator, error) {
+	if err := checkIndex(index); err != nil {
+		return nil, err
+	}
+	return func(word string) []string { return Overwrite(word, index) }, nil
+}
+
+// ToggleGenerator creates a generator for toggle rules
+//
+// Args:
+//
+//	index (int): Position of the first character
+//
+// Returns:
+//
+//	(Generator): Generator that calls Toggle
+//	(error): ErrInvalidArgument if the index is not valid
+func ToggleGenerator(index int) (Generator, error) {
+	if err := checkIndex(index); err != nil {
+		return nil, err
+	}
+	return func(word string) []string { return Toggle(word, index) }, nil
+}
+
+// CharsGenerator creates a generator for custom rules per character
+//
+// Args:
+//
+//	rule (string): String that is used in the operation
+//
+// Returns:
+//
+//	(Generator): Generator that calls Chars
+//	(error): ErrInvalidArgument if the rule is empty
+func CharsGenerator(rule string) (Generator, error) {
+	if rule == "" {
+		return nil, fmt.Errorf("%w: chars mode needs a rule", ErrInvalidArgument)
+	}
+	return func(word string) []string { return Chars(word, rule) }, nil
+}
+
+// ComboGenerator creates a generator for a combination of rule modes
+//
+// Args:
+//
+//	modeA (string): First mode to use in the operation
+//	modeB (string): Second mode to use in the operation
+//
+// Returns:
+//
+//	(Generator): Generator that calls Combo
+//	(error): ErrInvalidArgument if a mode is not valid
+func ComboGenerator(modeA string, modeB string) (Generator, error) {
+	for _, mode := range []string{modeA, modeB} {
+		if err := checkMode("combo", mode, "toggle", "prepend", "append", "insert"); err != nil {
+			return nil, err
+		}
+	}
+	return func(word string) []string { return Combo(word, modeA, modeB) }, nil
+}
+
+// LeetGenerator creates a generator for leetspeak substitution rules
+//
+// Args:
+//
+//	table (LeetTable): Mapping of characters to replacements
+//	mode (string): Kind of substitutions to create
+//	limit (int): Maximum number of rules per line of text
+//
+// Returns:
+//
+//	(Generator): Generator that calls Leet
+//	(error): ErrInvalidArgument if an argument is not valid
+func LeetGenerator(table LeetTable, mode string, limit int) (Generator, error) {
+	if err := checkMode("leet", mode, "full", "partial", "positional", "all"); err != nil {
+		return nil, err
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf("%w: leet limit %d must be at least 1", ErrInvalidArgument, limit)
+	}
+	if len(table) == 0 {
+		table = DefaultLeetTable
+	}
+	return func(word string) []string { return Leet(word, table, mode, limit) }, nil
+}
@@ -0,0 +1,246 @@
+package rule
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jakewnuk/rulecat/pkg/parser"
+	"github.com/jakewnuk/rulecat/pkg/validate"
+)
+
+// LeetTable maps characters to their leetspeak replacements
+//
+// # Upper case letters use the replacements of their lower case letter when
+// they have no entry of their own
+type LeetTable map[byte][]byte
+
+// DefaultLeetTable is the mapping used when no table is given
+var DefaultLeetTable = LeetTable{
+	'a': []byte("@4"),
+	'b': []byte("8"),
+	'e': []byte("3"),
+	'g': []byte("9"),
+	'i': []byte("1!"),
+	'l': []byte("1"),
+	'o': []byte("0"),
+	's': []byte("$5"),
+	't': []byte("7"),
+	'z': []byte("2"),
+}
+
+// DefaultLeetLimit is the default maximum number of rules per line of text
+const DefaultLeetLimit = 100
+
+// ParseLeetTable reads a mapping table with one character per line
+//
+// # Lines are written as a=@4 with the character, an equals sign, and each
+// replacement character. Empty lines and lines starting with # are ignored
+//
+// Args:
+//
+//	text ([]byte): Contents of the table
+//
+// Returns:
+//
+//	(LeetTable): Mapping table
+//	(error): ErrInvalidArgument if a line is not valid
+func ParseLeetTable(text []byte) (LeetTable, error) {
+	table := make(LeetTable)
+	for i, line := range strings.Split(string(text), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		from, to, ok := strings.Cut(line, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || len(from) != 1 || to == "" {
+			return nil, fmt.Errorf("%w: leet table line %d: %q must be a character, =, and its replacements", ErrInvalidArgument, i+1, line)
+		}
+		table[from[0]] = append(table[from[0]], to...)
+	}
+	if len(table) == 0 {
+		return nil, fmt.Errorf("%w: leet table has no mappings", ErrInvalidArgument)
+	}
+	return table, nil
+}
+
+// replacements finds the replacements of a character
+func (t LeetTable) replacements(c byte) []byte {
+	if to, ok := t[c]; ok {
+		return to
+	}
+	if c >= 'A' && c <= 'Z' {
+		return t[c+32]
+	}
+	return nil
+}
+
+// Leet creates leetspeak substitution rules for the characters of text
+//
+//	# Valid modes are:
+//	- full substitutes every mapped character with s rules
+//	- partial substitutes some of the mapped characters with s rules
+//	- positional substitutes single occurrences with o rules
+//	- all creates the rules of every mode
+//
+// Args:
+//
+//	word (string): Text to find mapped characters in
+//	table (LeetTable): Mapping of characters to replacements
+//	mode (string): Kind of substitutions to create
+//	limit (int): Maximum number of rules to create
+//
+// Returns:
+//
+//	([]string): Rules created from the text or none if no character is mapped
+func Leet(word string, table LeetTable, mode string, limit int) []string {
+	var present []byte
+	var occurrences []int
+	seen := make(map[byte]bool)
+	for i := 0; i < len(word); i++ {
+		c := word[i]
+		if len(table.replacements(c)) == 0 {
+			continue
+		}
+		if !seen[c] {
+			seen[c] = true
+			present = append(present, c)
+		}
+		if i <= parser.MaxPosition {
+			occurrences = append(occurrences, i)
+		}
+	}
+	if len(present) == 0 {
+		return nil
+	}
+
+	var rules []string
+	emit := func(ops []parser.Operation) bool {
+		rules = append(rules, parser.Format(ops))
+		return len(rules) < limit
+	}
+
+	// substitute builds s rules for the selected characters
+	substitute := func(chars []byte) bool {
+		return leetChoices(chars, table, func(picks []byte) bool {
+			ops := make([]parser.Operation, len(chars))
+			for i, c := range chars {
+				ops[i] = parser.Operation{Opcode: 's', Chars: []byte{c, picks[i]}}
+			}
+			return emit(ops)
+		})
+	}
+
+	// overwrite builds o rules for the selected positions
+	overwrite := func(positions []int) bool {
+		chars := make([]byte, len(positions))
+		for i, p := range positions {
+			chars[i] = word[p]
+		}
+		return leetChoices(chars, table, func(picks []byte) bool {
+			ops := make([]parser.Operation, len(positions))
+			for i, p := range positions {
+				ops[i] = parser.Operation{Opcode: 'o', Positions: []int{p}, Chars: []byte{picks[i]}}
+			}
+			return emit(ops)
+		})
+	}
+
+	full := mode == "full" || mode == "all"
+	partial := mode == "partial" || mode == "all"
+	positional := mode == "positional" || mode == "all"
+
+	if full && len(present) <= validate.MaxFunctions && !substitute(present) {
+		return rules
+	}
+	if partial {
+		for k := 1; k < len(present) && k <= validate.MaxFunctions; k++ {
+			if !combinations(len(present), k, func(idx []int) bool {
+				chars := make([]byte, len(idx))
+				for i, j := range idx {
+					chars[i] = present[j]
+				}
+				return substitute(chars)
+			}) {
+				return rules
+			}
+		}
+	}
+	if positional {
+		for k := 1; k <= len(occurrences) && k <= validate.MaxFunctions; k++ {
+			if !combinations(len(occurrences), k, func(idx []int) bool {
+				positions := make([]int, len(idx))
+				for i, j := range idx {
+					positions[i] = occurrences[j]
+				}
+				return overwrite(positions)
+			}) {
+				return rules
+			}
+		}
+	}
+	return rules
+}
+
+// leetChoices calls a function with every choice of replacement for each
+// character until it returns false
+//
+// Args:
+//
+//	chars ([]byte): Characters to replace
+//	table (LeetTable): Mapping of characters to replacements
+//	fn (func([]byte) bool): Function called with a replacement per character
+//
+// Returns:
+//
+//	(bool): False if fn stopped the iteration
+func leetChoices(chars []byte, table LeetTable, fn func(picks []byte) bool) bool {
+	picks := make([]byte, len(chars))
+	var choose func(i int) bool
+	choose = func(i int) bool {
+		if i == len(chars) {
+			return fn(picks)
+		}
+		for _, to := range table.replacements(chars[i]) {
+			if to == chars[i] {
+				continue
+			}
+			picks[i] = to
+			if !choose(i + 1) {
+				return false
+			}
+		}
+		return true
+	}
+	return choose(0)
+}
+
+// combinations calls a function with every k sized combination of the
+// indexes below n in lexical order until it returns false
+//
+// Args:
+//
+//	n (int): Number of items
+//	k (int): Size of each combination
+//	fn (func([]int) bool): Function called with each combination
+//
+// Returns:
+//
+//	(bool): False if fn stopped the iteration
+func combinations(n int, k int, fn func(idx []int) bool) bool {
+	idx := make([]int, k)
+	var pick func(i int, start int) bool
+	pick = func(i int, start int) bool {
+		if i == k {
+			return fn(idx)
+		}
+		for j := start; j <= n-(k-i); j++ {
+			idx[i] = j
+			if !pick(i+1, j+1) {
+				return false
+			}
+		}
+		return true
+	}
+	return pick(0, 0)
+}
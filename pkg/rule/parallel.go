@@ -0,0 +1,169 @@
+package rule
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jakewnuk/rulecat/pkg/dialect"
+)
+
+// ChunkSize is the number of lines each worker processes at a time
+const ChunkSize = 4096
+
+// chunk is a run of input lines and the rules created from them
+type chunk struct {
+	// seq is the position of the chunk in the input
+	seq   int
+	lines []string
+	out   bytes.Buffer
+}
+
+// StreamParallel runs a generator over each line of a reader across worker
+// goroutines and writes the rules in input order
+//
+// # Lines are read in chunks of ChunkSize and each chunk is written with a
+// single call to w. A single worker is the same as Stream
+//
+// Args:
+//
+//	r (io.Reader): Lines of text
+//	w (io.Writer): Destination for the rules
+//	gen (Generator): Generator to run on each line, called concurrently
+//	d (dialect.Dialect): Syntax to write rules in
+//	threads (int): Number of worker goroutines
+//
+// Returns:
+//
+//	(error): ErrRead or ErrLineTooLong if reading fails and ErrWrite if
+//	writing fails
+func StreamParallel(r io.Reader, w io.Writer, gen Generator, d dialect.Dialect, threads int) error {
+	if threads <= 1 {
+		return Stream(r, w, gen, d)
+	}
+	if header := d.Header(); header != "" {
+		if _, err := fmt.Fprintln(w, header); err != nil {
+			return WriteError(err)
+		}
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// tokens limits the chunks held in memory while an earlier chunk is
+	// still being processed
+	tokens := make(chan struct{}, threads*4)
+	jobs := make(chan *chunk, threads)
+	results := make(chan *chunk, threads)
+
+	var readErr error
+	go func() {
+		defer close(jobs)
+		readErr = readChunks(r, jobs, tokens, done)
+	}()
+
+	finished := make(chan struct{})
+	for i := 0; i < threads; i++ {
+		go func() {
+			defer func() { finished <- struct{}{} }()
+			for c := range jobs {
+				renderChunk(c, gen, d)
+				select {
+				case results <- c:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		for i := 0; i < threads; i++ {
+			<-finished
+		}
+		close(results)
+	}()
+
+	pending := make(map[int]*chunk)
+	next := 0
+	for c := range results {
+		pending[c.seq] = c
+		for {
+			c, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if _, err := w.Write(c.out.Bytes()); err != nil {
+				return WriteError(err)
+			}
+			<-tokens
+		}
+	}
+	return readErr
+}
+
+// readChunks splits the lines of a reader into chunks
+//
+// Args:
+//
+//	r (io.Reader): Lines of text
+//	jobs (chan<- *chunk): Destination for the chunks
+//	tokens (chan struct{}): Slots for chunks that are not written yet
+//	done (<-chan struct{}): Closed when the chunks are no longer needed
+//
+// Returns:
+//
+//	(error): ErrRead or ErrLineTooLong if reading fails
+func readChunks(r io.Reader, jobs chan<- *chunk, tokens chan struct{}, done <-chan struct{}) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	c := &chunk{}
+	send := func() bool {
+		select {
+		case tokens <- struct{}{}:
+		case <-done:
+			return false
+		}
+		select {
+		case jobs <- c:
+		case <-done:
+			return false
+		}
+		c = &chunk{seq: c.seq + 1}
+		return true
+	}
+
+	for scanner.Scan() {
+		line++
+		c.lines = append(c.lines, scanner.Text())
+		if len(c.lines) == ChunkSize && !send() {
+			return nil
+		}
+	}
+	if len(c.lines) > 0 && !send() {
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return ReadError(err, line+1)
+	}
+	return nil
+}
+
+// renderChunk runs a generator over the lines of a chunk into its output
+func renderChunk(c *chunk, gen Generator, d dialect.Dialect) {
+	for _, word := range c.lines {
+		for _, rule := range gen(word) {
+			output, err := d.Render(rule)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING: skipping %q: %s\n", rule, err)
+				continue
+			}
+			c.out.WriteString(output)
+			c.out.WriteByte('\n')
+		}
+	}
+	c.lines = nil
+}
@@ -1,213 +1,173 @@
-// Package rule contains the CLI logic for rulecat
+// Package rule contains the logic for creating rules from text
+//
+// # Each generator turns one line of text into rules and the *Rules functions
+// stream generators from an io.Reader to an io.Writer
 package rule
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"regexp"
-	"strconv"
 	"strings"
 
 	"github.com/jakewnuk/rulecat/pkg/utils"
 )
 
-// AppendRules will turn stdin to append rules
+// Regexes for prepend combos to find camel case
+var (
+	preReMatch1 = regexp.MustCompile(`[A-Z].*[A-Z]`)
+	preReParse1 = regexp.MustCompile(`^([A-Z][a-z]+)`)
+	preReMatch2 = regexp.MustCompile(`[a-z].*[A-Z]`)
+	preReParse2 = regexp.MustCompile(`^([a-z][a-z]+)`)
+)
+
+// appReRemove removes alpha characters for append combos
+var appReRemove = regexp.MustCompile(`[a-zA-Z]`)
+
+// insertReMatch finds i[0-9]<SPECIAL CHARACTER> rules for insert combos
+var insertReMatch = regexp.MustCompile(`i[0-9][!@#\$%\^&\*\(\)_\+\-\=\{\}\[\]\\\|;:'",<\.>\/\?~]`)
+
+// Append creates an append rule from text
 //
 // Args:
 //
-//	stdIn (*bufio.Scanner): Standard input as a buffer
-//	mode (string): Mode function to use to modify operation
+//	word (string): Text to append
+//	mode (string): Mode function to use to modify operation (remove, shift)
 //
 // Returns:
 //
-//	None
-func AppendRules(stdIn *bufio.Scanner, mode string) {
+//	([]string): Rules created from the text
+func Append(word string, mode string) []string {
+	rule := utils.CharToRule(word, "$")
 	switch mode {
 	// remove will remove characters then append
 	case "remove":
-		for stdIn.Scan() {
-			rule := utils.CharToRule(stdIn.Text(), "$")
-			remove := utils.LenToRule(stdIn.Text(), "]")
-			utils.PrintCharacterRuleOutput(remove, rule)
-		}
+		return characterRule(utils.LenToRule(word, "]"), rule)
 	// shift will shift characters back to front then append
 	case "shift":
-		for stdIn.Scan() {
-			rule := utils.CharToRule(stdIn.Text(), "$")
-			shift := utils.LenToRule(stdIn.Text(), "}")
-			utils.PrintCharacterRuleOutput(shift, rule)
-		}
-	default:
-		for stdIn.Scan() {
-			rule := utils.CharToRule(stdIn.Text(), "$")
-			utils.PrintCharacterRuleOutput(rule)
-		}
+		return characterRule(utils.LenToRule(word, "}"), rule)
 	}
-
+	return characterRule(rule)
 }
 
-// PrependRules will turn stdin to prepend rules
+// Prepend creates a prepend rule from text
 //
 // Args:
 //
-//	stdIn (*bufio.Scanner): Standard input as a buffer
-//	mode (string): Mode function to use to modify operation
+//	word (string): Text to prepend
+//	mode (string): Mode function to use to modify operation (remove, shift)
 //
 // Returns:
 //
-//	None
-func PrependRules(stdIn *bufio.Scanner, mode string) {
+//	([]string): Rules created from the text
+func Prepend(word string, mode string) []string {
+	rule := utils.CharToRule(utils.ReverseString(word), "^")
 	switch mode {
 	// remove will remove characters then prepend
 	case "remove":
-		for stdIn.Scan() {
-			rule := utils.CharToRule(utils.ReverseString(stdIn.Text()), "^")
-			remove := utils.LenToRule(stdIn.Text(), "[")
-			utils.PrintCharacterRuleOutput(remove, rule)
-		}
+		return characterRule(utils.LenToRule(word, "["), rule)
 	// shift will shift characters front to back then prepend
 	case "shift":
-		for stdIn.Scan() {
-			rule := utils.CharToRule(utils.ReverseString(stdIn.Text()), "^")
-			shift := utils.LenToRule(stdIn.Text(), "{")
-			utils.PrintCharacterRuleOutput(shift, rule)
-		}
-	default:
-		for stdIn.Scan() {
-			rule := utils.CharToRule(utils.ReverseString(stdIn.Text()), "^")
-			utils.PrintCharacterRuleOutput(rule)
-		}
+		return characterRule(utils.LenToRule(word, "{"), rule)
 	}
-
+	return characterRule(rule)
 }
 
-// InsertRules will turn stdin to insert rules starting at an index
+// Insert creates an insert rule from text starting at an index
 //
 // Args:
 //
-//	stdIn (*bufio.Scanner): Standard input as a buffer
-//	index (string): Integer of where to start the operation
+//	word (string): Text to insert
+//	index (int): Position of the first character
 //
 // Returns:
 //
-//	None
-func InsertRules(stdIn *bufio.Scanner, index string) {
-	i, err := strconv.Atoi(index)
-	if err != nil {
-		fmt.Printf("ERROR: %s\n", err)
-		os.Exit(1)
-	}
-	for stdIn.Scan() {
-		rule := utils.CharToIteratingRule(stdIn.Text(), "i", i)
-		fmt.Println(rule)
-	}
+//	([]string): Rules created from the text
+func Insert(word string, index int) []string {
+	return []string{utils.CharToIteratingRule(word, "i", index)}
 }
 
-// OverwriteRules will turn stdin to overwrite rules starting at an index
+// Overwrite creates an overwrite rule from text starting at an index
 //
 // Args:
 //
-//	stdIn (*bufio.Scanner): Standard input as a buffer
-//	index (string): Integer of where to start the operation
+//	word (string): Text to overwrite with
+//	index (int): Position of the first character
 //
 // Returns:
 //
-//	None
-func OverwriteRules(stdIn *bufio.Scanner, index string) {
-	i, err := strconv.Atoi(index)
-	if err != nil {
-		fmt.Printf("ERROR: %s\n", err)
-		os.Exit(1)
-	}
-	for stdIn.Scan() {
-		rule := utils.CharToIteratingRule(stdIn.Text(), "o", i)
-		fmt.Println(rule)
-	}
+//	([]string): Rules created from the text
+func Overwrite(word string, index int) []string {
+	return []string{utils.CharToIteratingRule(word, "o", index)}
 }
 
-// ToggleRules will turn stdin to toggle rules starting at an index
+// Toggle creates a toggle rule for the upper case characters of text
+// starting at an index
 //
 // Args:
 //
-//	stdIn (*bufio.Scanner): Standard input as a buffer
-//	index (string): Integer of where to start the operation
+//	word (string): Text to find upper case characters in
+//	index (int): Position of the first character
 //
 // Returns:
 //
-//	None
-func ToggleRules(stdIn *bufio.Scanner, index string) {
-	i, err := strconv.Atoi(index)
-	if err != nil {
-		fmt.Printf("ERROR: %s\n", err)
-		os.Exit(1)
-	}
-	for stdIn.Scan() {
-		rule := utils.StringToToggle(stdIn.Text(), "T", i)
-		if rule != "" {
-			fmt.Println(rule)
-		}
+//	([]string): Rules created from the text or none if nothing is upper case
+func Toggle(word string, index int) []string {
+	if rule := utils.StringToToggle(word, "T", index); rule != "" {
+		return []string{rule}
 	}
+	return nil
 }
 
-// BlankLines will print a blank line for each item in stdin for -a9
+// Blank creates a blank line for text for -a9
 //
 // Args:
 //
-//	stdIn (*bufio.Scanner): Standard input as a buffer
+//	word (string): Text to replace
 //
 // Returns:
 //
-//	None
-func BlankLines(stdIn *bufio.Scanner) {
-	for stdIn.Scan() {
-		fmt.Println("")
-	}
+//	([]string): A single empty line
+func Blank(word string) []string {
+	return []string{""}
 }
 
-// CartesianRules will create the Caresian product of stdin and the input file
+// Cartesian creates the Cartesian product of text and rule lines
 //
-// # Standard input will be placed before file content
+// # The text will be placed before each line
 //
 // Args:
 //
-//	stdIn (*bufio.Scanner): Standard input as a buffer
-//	file ([]byte): Lines of a file that are used in the operation
+//	word (string): Rule to place first
+//	lines ([]string): Rules to place second
 //
 // Returns:
 //
-//	None
-func CartesianRules(stdIn *bufio.Scanner, file []byte) {
-	fileLines := strings.Split(string(file), "\n")
-	for stdIn.Scan() {
-		input := stdIn.Text()
-		for _, line := range fileLines {
-			if line != "" {
-				fmt.Printf("%s %s\n", input, line)
-			}
+//	([]string): Rules created from the text
+func Cartesian(word string, lines []string) []string {
+	var rules []string
+	for _, line := range lines {
+		if line != "" {
+			rules = append(rules, fmt.Sprintf("%s %s", word, line))
 		}
 	}
+	return rules
 }
 
-// CharsToRules will insert a custom rule before each character
+// Chars inserts a custom rule before each character of text
 //
 // Args:
 //
-//	stdIn (*bufio.Scanner): Standard input as a buffer
+//	word (string): Text to create the rule from
 //	rule (string): String that is used in the operation
 //
 // Returns:
 //
-//	None
-func CharsToRules(stdIn *bufio.Scanner, rule string) {
-	for stdIn.Scan() {
-		rule := utils.CharToRule(stdIn.Text(), rule)
-		utils.PrintCharacterRuleOutput(rule)
-	}
-
+//	([]string): Rules created from the text
+func Chars(word string, rule string) []string {
+	return characterRule(utils.CharToRule(word, rule))
 }
 
-// ComboRules will create a combination of rule modes for each line
+// Combo creates a combination of rule modes from text
 //
 //	# Valid modes are:
 //	- toggle
@@ -217,101 +177,79 @@ func CharsToRules(stdIn *bufio.Scanner, rule string) {
 //
 // Args:
 //
-//	stdIn (*bufio.Scanner): Standard input as a buffer
+//	word (string): Text to create the rule from
 //	modeA (string): First mode to use in the operation
 //	modeB (string): Second mode to use in the operation
 //
 // Returns:
 //
-//	None
-func ComboRules(stdIn *bufio.Scanner, modeA string, modeB string) {
-	// Regexes for prepend mode to find camel case
-	var prefixEntry string
-	preReMatch1 := regexp.MustCompile(`[A-Z].*[A-Z]`)
-	preReParse1 := regexp.MustCompile(`^([A-Z][a-z]+)`)
-	preReMatch2 := regexp.MustCompile(`[a-z].*[A-Z]`)
-	preReParse2 := regexp.MustCompile(`^([a-z][a-z]+)`)
-
-	// Regex for append mode to remove alpha characters
-	appReRemove := regexp.MustCompile(`[a-zA-Z]`)
-
-	// Regex for insert mode to find i[0-9]<SPECIAL CHARACTER> rules
-	insertReMatch := regexp.MustCompile(`i[0-9][!@#\$%\^&\*\(\)_\+\-\=\{\}\[\]\\\|;:'",<\.>\/\?~]`)
-
-	for stdIn.Scan() {
-		resultA := ""
-		resultB := ""
-
-		switch modeA {
-		case "toggle":
-			resultA += utils.StringToToggle(stdIn.Text(), "T", 0)
-		case "prepend":
-			if preReMatch1.MatchString(stdIn.Text()) {
-				prefixEntry = preReParse1.FindString(stdIn.Text())
-
-				if modeB == "toggle" {
-					prefixEntry = strings.ToLower(prefixEntry)
-				}
-
-				resultA += utils.CharToRule(utils.ReverseString(prefixEntry), "^")
-			} else if preReMatch2.MatchString(stdIn.Text()) {
-				prefixEntry = preReParse2.FindString(stdIn.Text())
-
-				if modeB == "toggle" {
-					prefixEntry = strings.ToLower(prefixEntry)
-				}
-
-				resultA += utils.CharToRule(utils.ReverseString(prefixEntry), "^")
-			}
-		case "append":
-			nonAlphaEntry := appReRemove.ReplaceAllString(stdIn.Text(), "")
-
-			if modeB == "toggle" {
-				nonAlphaEntry = strings.ToLower(nonAlphaEntry)
-			}
+//	([]string): Rules created from the text or none if a mode has no result
+func Combo(word string, modeA string, modeB string) []string {
+	resultA := comboPart(word, modeA, modeB)
+	resultB := comboPart(word, modeB, modeA)
+	if len(resultA) > 1 && len(resultB) > 1 {
+		return characterRule(resultA + " " + resultB)
+	}
+	return nil
+}
 
-			resultA += utils.CharToRule(nonAlphaEntry, "$")
-		case "insert":
-			insertRule := utils.CharToIteratingRule(stdIn.Text(), "i", 0)
-			resultA += insertReMatch.FindString(insertRule)
+// comboPart creates the rule for a single mode of a combo
+//
+// Args:
+//
+//	word (string): Text to create the rule from
+//	mode (string): Mode to create the rule for
+//	other (string): Mode the rule is combined with
+//
+// Returns:
+//
+//	(string): Rule for the mode or an empty string
+func comboPart(word string, mode string, other string) string {
+	switch mode {
+	case "toggle":
+		return utils.StringToToggle(word, "T", 0)
+	case "prepend":
+		prefixEntry := ""
+		if preReMatch1.MatchString(word) {
+			prefixEntry = preReParse1.FindString(word)
+		} else if preReMatch2.MatchString(word) {
+			prefixEntry = preReParse2.FindString(word)
+		} else {
+			return ""
 		}
 
-		switch modeB {
-		case "toggle":
-			resultB += utils.StringToToggle(stdIn.Text(), "T", 0)
-		case "prepend":
-			if preReMatch1.MatchString(stdIn.Text()) {
-				prefixEntry = preReParse1.FindString(stdIn.Text())
-
-				if modeA == "toggle" {
-					prefixEntry = strings.ToLower(prefixEntry)
-				}
-
-				resultB += utils.CharToRule(utils.ReverseString(prefixEntry), "^")
-			} else if preReMatch2.MatchString(stdIn.Text()) {
-				prefixEntry = preReParse2.FindString(stdIn.Text())
-
-				if modeA == "toggle" {
-					prefixEntry = strings.ToLower(prefixEntry)
-				}
-
-				resultB += utils.CharToRule(utils.ReverseString(prefixEntry), "^")
-			}
-		case "append":
-			nonAlphaEntry := appReRemove.ReplaceAllString(stdIn.Text(), "")
+		if other == "toggle" {
+			prefixEntry = strings.ToLower(prefixEntry)
+		}
 
-			if modeA == "toggle" {
-				nonAlphaEntry = strings.ToLower(nonAlphaEntry)
-			}
+		return utils.CharToRule(utils.ReverseString(prefixEntry), "^")
+	case "append":
+		nonAlphaEntry := appReRemove.ReplaceAllString(word, "")
 
-			resultB += utils.CharToRule(nonAlphaEntry, "$")
-		case "insert":
-			insertRule := utils.CharToIteratingRule(stdIn.Text(), "i", 0)
-			resultB += insertReMatch.FindString(insertRule)
+		if other == "toggle" {
+			nonAlphaEntry = strings.ToLower(nonAlphaEntry)
 		}
 
-		if len(resultA) > 1 && len(resultB) > 1 {
-			utils.PrintCharacterRuleOutput(resultA + " " + resultB)
-		}
+		return utils.CharToRule(nonAlphaEntry, "$")
+	case "insert":
+		insertRule := utils.CharToIteratingRule(word, "i", 0)
+		return insertReMatch.FindString(insertRule)
+	}
+	return ""
+}
+
+// characterRule joins and converts CharToRule output into a rule
+//
+// Args:
+//
+//	strs (...string): Input strings to join
+//
+// Returns:
+//
+//	([]string): The rule or none if it is empty or too long
+func characterRule(strs ...string) []string {
+	if output := utils.FormatCharacterRuleOutput(strs...); output != "" {
+		return []string{output}
 	}
+	return nil
 }
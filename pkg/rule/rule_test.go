@@ -0,0 +1,185 @@
+package rule
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jakewnuk/rulecat/pkg/dialect"
+)
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		name string
+		gen  Generator
+		word string
+		want []string
+	}{
+		{"append", func(w string) []string { return Append(w, "") }, "ab1", []string{"$a $b $1"}},
+		{"append remove", func(w string) []string { return Append(w, "remove") }, "ab", []string{"] ] $a $b"}},
+		{"append shift", func(w string) []string { return Append(w, "shift") }, "ab", []string{"} } $a $b"}},
+		{"prepend", func(w string) []string { return Prepend(w, "") }, "ab1", []string{"^1 ^b ^a"}},
+		{"prepend remove", func(w string) []string { return Prepend(w, "remove") }, "ab", []string{"[ [ ^b ^a"}},
+		{"insert", func(w string) []string { return Insert(w, 2) }, "ab", []string{"i2a i3b"}},
+		{"overwrite", func(w string) []string { return Overwrite(w, 0) }, "ab", []string{"o0a o1b"}},
+		{"toggle", func(w string) []string { return Toggle(w, 0) }, "aBc", []string{"T1"}},
+		{"toggle none", func(w string) []string { return Toggle(w, 0) }, "abc", nil},
+		{"blank", Blank, "abc", []string{""}},
+		{"cartesian", func(w string) []string { return Cartesian(w, []string{"$1", "", "$2"}) }, "u", []string{"u $1", "u $2"}},
+		{"chars", func(w string) []string { return Chars(w, "@") }, "ab", []string{"@a @b"}},
+		{"combo", func(w string) []string { return Combo(w, "toggle", "append") }, "Pass12", []string{"T0 $1 $2"}},
+		{"combo none", func(w string) []string { return Combo(w, "toggle", "append") }, "pass", nil},
+		{"too long", func(w string) []string { return Append(w, "") }, strings.Repeat("a", 32), nil},
+		{"leet full", func(w string) []string { return Leet(w, DefaultLeetTable, "full", 10) }, "Bass", []string{"sB8 sa@ ss$", "sB8 sa@ ss5", "sB8 sa4 ss$", "sB8 sa4 ss5"}},
+		{"leet partial", func(w string) []string { return Leet(w, DefaultLeetTable, "partial", 10) }, "eo", []string{"se3", "so0"}},
+		{"leet positional", func(w string) []string { return Leet(w, DefaultLeetTable, "positional", 10) }, "oxo", []string{"o00", "o20", "o00 o20"}},
+		{"leet all", func(w string) []string { return Leet(w, DefaultLeetTable, "all", 10) }, "to", []string{"st7 so0", "st7", "so0", "o07", "o10", "o07 o10"}},
+		{"leet limit", func(w string) []string { return Leet(w, DefaultLeetTable, "all", 3) }, "password", []string{"sa@ ss$ so0", "sa@ ss5 so0", "sa4 ss$ so0"}},
+		{"leet none", func(w string) []string { return Leet(w, DefaultLeetTable, "all", 10) }, "xyc1", nil},
+	}
+
+	for _, test := range tests {
+		got := test.gen(test.word)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s(%q) = %q; want %q", test.name, test.word, got, test.want)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	var out bytes.Buffer
+	err := AppendRules(strings.NewReader("ab\n12\n"), &out, "", dialect.John{Section: "test"})
+	if err != nil {
+		t.Fatalf("AppendRules() error = %v", err)
+	}
+
+	want := "[List.Rules:test]\nAz\"ab\"\nAz\"12\"\n"
+	if out.String() != want {
+		t.Errorf("AppendRules() wrote %q; want %q", out.String(), want)
+	}
+}
+
+// failingWriter returns an error for every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestStreamErrors(t *testing.T) {
+	long := strings.Repeat("a", MaxLineLength+1)
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"bad index", InsertRules(strings.NewReader("ab\n"), &bytes.Buffer{}, 36, dialect.Hashcat{}), ErrInvalidArgument},
+		{"negative index", ToggleRules(strings.NewReader("ab\n"), &bytes.Buffer{}, -1, dialect.Hashcat{}), ErrInvalidArgument},
+		{"bad mode", AppendRules(strings.NewReader("ab\n"), &bytes.Buffer{}, "bogus", dialect.Hashcat{}), ErrInvalidArgument},
+		{"bad combo", ComboRules(strings.NewReader("ab\n"), &bytes.Buffer{}, "toggle", "bogus"), ErrInvalidArgument},
+		{"empty chars", CharsToRules(strings.NewReader("ab\n"), &bytes.Buffer{}, ""), ErrInvalidArgument},
+		{"bad leet mode", LeetRules(strings.NewReader("ab\n"), &bytes.Buffer{}, nil, "bogus", 1, dialect.Hashcat{}), ErrInvalidArgument},
+		{"bad leet limit", LeetRules(strings.NewReader("ab\n"), &bytes.Buffer{}, nil, "full", 0, dialect.Hashcat{}), ErrInvalidArgument},
+		{"long line", AppendRules(strings.NewReader("ab\n"+long+"\n"), &bytes.Buffer{}, "", dialect.Hashcat{}), ErrLineTooLong},
+		{"write", AppendRules(strings.NewReader("ab\n"), failingWriter{}, "", dialect.Hashcat{}), ErrWrite},
+	}
+
+	for _, test := range tests {
+		if !errors.Is(test.err, test.want) {
+			t.Errorf("%s: error = %v; want %v", test.name, test.err, test.want)
+		}
+	}
+}
+
+// stringSource returns a product source that reads lines from a string
+func stringSource(text string) Source {
+	return func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(text)), nil }
+}
+
+func TestProduct(t *testing.T) {
+	long := strings.TrimSpace(strings.Repeat("$1 ", 30))
+	sources := []Source{stringSource("u\n\n$1\n"), stringSource("$2\n$2\n")}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"product", "l\n", "l u $2\nl u $2\nl $1 $2\nl $1 $2\n"},
+		{"empty lines", "l\n\r\n\n", "l u $2\nl u $2\nl $1 $2\nl $1 $2\n"},
+		{"function limit", long + "\n:\n", ": u $2\n: u $2\n: $1 $2\n: $1 $2\n"},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		if err := Product(strings.NewReader(test.text), &out, sources, dialect.Hashcat{}); err != nil {
+			t.Fatalf("%s: Product() error = %v", test.name, err)
+		}
+		if out.String() != test.want {
+			t.Errorf("%s: Product() wrote %q; want %q", test.name, out.String(), test.want)
+		}
+	}
+
+	counts, total, err := ProductSize(sources)
+	if err != nil {
+		t.Fatalf("ProductSize() error = %v", err)
+	}
+	if !reflect.DeepEqual(counts, []int{2, 2}) || total != 4 {
+		t.Errorf("ProductSize() = %v, %d; want [2 2], 4", counts, total)
+	}
+}
+
+func TestStreamParallel(t *testing.T) {
+	var text strings.Builder
+	for i := 0; i < ChunkSize*3+7; i++ {
+		fmt.Fprintf(&text, "Word%d\n", i)
+	}
+	gen, err := ComboGenerator("toggle", "append")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want bytes.Buffer
+	if err := Stream(strings.NewReader(text.String()), &want, gen, dialect.John{Section: "test"}); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	for _, threads := range []int{1, 2, 8} {
+		var out bytes.Buffer
+		if err := StreamParallel(strings.NewReader(text.String()), &out, gen, dialect.John{Section: "test"}, threads); err != nil {
+			t.Fatalf("StreamParallel(%d) error = %v", threads, err)
+		}
+		if out.String() != want.String() {
+			t.Errorf("StreamParallel(%d) output differs from Stream", threads)
+		}
+	}
+
+	long := strings.Repeat("a", MaxLineLength+1)
+	if err := StreamParallel(strings.NewReader("ab\n"+long+"\n"), &bytes.Buffer{}, gen, dialect.Hashcat{}, 4); !errors.Is(err, ErrLineTooLong) {
+		t.Errorf("StreamParallel() long line error = %v; want %v", err, ErrLineTooLong)
+	}
+	if err := StreamParallel(strings.NewReader(text.String()), failingWriter{}, gen, dialect.Hashcat{}, 4); !errors.Is(err, ErrWrite) {
+		t.Errorf("StreamParallel() write error = %v; want %v", err, ErrWrite)
+	}
+}
+
+func TestParseLeetTable(t *testing.T) {
+	table, err := ParseLeetTable([]byte("# custom\na=@4\n\n e = 3\r\nh=#\n"))
+	if err != nil {
+		t.Fatalf("ParseLeetTable() error = %v", err)
+	}
+	want := LeetTable{'a': []byte("@4"), 'e': []byte("3"), 'h': []byte("#")}
+	if !reflect.DeepEqual(table, want) {
+		t.Errorf("ParseLeetTable() = %q; want %q", table, want)
+	}
+
+	for _, text := range []string{"ab=1\n", "a\n", "a=\n", "# empty\n"} {
+		if _, err := ParseLeetTable([]byte(text)); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("ParseLeetTable(%q) error = %v; want %v", text, err, ErrInvalidArgument)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package rule
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jakewnuk/rulecat/pkg/dialect"
+)
+
+// Generator creates rules from a single line of text
+type Generator func(word string) []string
+
+// Stream runs a generator over each line of a reader and writes the rules
+//
+// # Rules that cannot be rendered in the dialect are reported to stderr and
+// skipped
+//
+// Args:
+//
+//	r (io.Reader): Lines of text
+//	w (io.Writer): Destination for the rules
+//	gen (Generator): Generator to run on each line
+//	d (dialect.Dialect): Syntax to write rules in
+//
+// Returns:
+//
+//	(error): ErrRead or ErrLineTooLong if reading fails and ErrWrite if
+//	writing fails
+func Stream(r io.Reader, w io.Writer, gen Generator, d dialect.Dialect) error {
+	if header := d.Header(); header != "" {
+		if _, err := fmt.Fprintln(w, header); err != nil {
+			return WriteError(err)
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		for _, rule := range gen(scanner.Text()) {
+			output, err := d.Render(rule)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING: skipping %q: %s\n", rule, err)
+				continue
+			}
+			if _, err := fmt.Fprintln(w, output); err != nil {
+				return WriteError(err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ReadError(err, line+1)
+	}
+	return nil
+}
+
+// AppendRules writes append rules for each line of a reader
+//
+// Args:
+//
+//	r (io.Reader): Lines of text
+//	w (io.Writer): Destination for the rules
+//	mode (string): Mode function to use to modify operation
+//	d (dialect.Dialect): Syntax to write rules in
+//
+// Returns:
+//
+//	(error): ErrInvalidArgument if an argument is not valid or an error from
+//	Stream
+func AppendRules(r io.Reader, w io.Writer, mode string, d dialect.Dialect) error {
+	gen, err := AppendGenerator(mode)
+	if err != nil {
+		return err
+	}
+	return Stream(r, w, gen, d)
+}
+
+// PrependRules writes prepend rules for each line of a reader
+//
+// Args:
+//
+//	r (io.Reader): Lines of text
+//	w (io.Writer): Destination for the rules
+//	mode (string): Mode function to use to modify operation
+//	d (dialect.Dialect): Syntax to write rules in
+//
+// Returns:
+//
+//	(error): ErrInvalidArgument if an argument is not valid or an error from
+//	Stream
+func PrependRules(r io.Reader, w io.Writer, mode string, d dialect.Dialect) error {
+	gen, err := PrependGenerator(mode)
+	if err != nil {
+		return err
+	}
+	return Stream(r, w, gen, d)
+}
+
+// InsertRules writes insert rules starting at an index for each line of a
+// reader
+//
+// Args:
+//
+//	r (io.Reader): Lines of text
+//	w (io.Writer): Destination for the rules
+//	index (int): Position of the first character
+//	d (dialect.Dialect): Syntax to write rules in
+//
+// Returns:
+//
+//	(error): ErrInvalidArgument if an argument is not valid or an error from
+//	Stream
+func InsertRules(r io.Reader, w io.Writer, index int, d dialect.Dialect) error {
+	gen, err := InsertGenerator(index)
+	if err != nil {
+		return err
+	}
+	return Stream(r, w, gen, d)
+}
+
+// OverwriteRules writes overwrite rules starting at an index for each line
+// of a reader
+//
+// Args:
+//
+//	r (io.Reader): Lines of text
+//	w (io.Writer): Destination for the rules
+//	index (int): Position of the first character
+//	d (dialect.Dialect): Syntax to write rules in
+//
+// Returns:
+//
+//	(error): ErrInvalidArgument if an argument is not valid or an error from
+//	Stream
+func OverwriteRules(r io.Reader, w io.Writer, index int, d dialect.Dialect) error {
+	gen, err := OverwriteGenerator(index)
+	if err != nil {
+		return err
+	}
+	return Stream(r, w, gen, d)
+}
+
+// ToggleRules writes toggle rules starting at an index for each line of a
+// reader
+//
+// Args:
+//
+//	r (io.Reader): Lines of text
+//	w (io.Writer): Destination for the rules
+//	index (int): Position of the first character
+//	d (dialect.Dialect): Syntax to write rules in
+//
+// Returns:
+//
+//	(error): ErrInvalidArgument if an argument is not valid or an error from
+//	Stream
+func ToggleRules(r io.Reader, w io.Writer, index int, d dialect.Dialect) error {
+	gen, err := ToggleGenerator(index)
+	if err != nil {
+		return err
+	}
+	return Stream(r, w, gen, d)
+}
+
+// LeetRules writes leetspeak substitution rules for each line of a reader
+//
+// Args:
+//
+//	r (io.Reader): Lines of text
+//	w (io.Writer): Destination for the rules
+//	table (LeetTable): Mapping of characters to replacements
+//	mode (string): Kind of substitutions to create
+//	limit (int): Maximum number of rules per line of text
+//	d (dialect.Dialect): Syntax to write rules in
+//
+// Returns:
+//
+//	(error): ErrInvalidArgument if an argument is not valid or an error from
+//	Stream
+func LeetRules(r io.Reader, w io.Writer, table LeetTable, mode string, limit int, d dialect.Dialect) error {
+	gen, err := LeetGenerator(table, mode, limit)
+	if err != nil {
+		return err
+	}
+	return Stream(r, w, gen, d)
+}
+
+// BlankLines writes a blank line for each line of a reader for -a9
+//
+// Args:
+//
+//	r (io.Reader): Lines of text
+//	w (io.Writer): Destination for the blank lines
+//
+// Returns:
+//
+//	(error): Error from Stream
+func BlankLines(r io.Reader, w io.Writer) error {
+	return Stream(r, w, Blank, dialect.Hashcat{})
+}
+
+// CartesianRules writes the Cartesian product of each line of a reader and
+// the lines of a file
+//
+// # Reader lines will be placed before file content
+//
+// Args:
+//
+//	r (io.Reader): Lines of text
+//	w (io.Writer): Destination for the rules
+//	file ([]byte): Lines of a file that are used in the operation
+//	d (dialect.Dialect): Syntax to write rules in
+//
+// Returns:
+//
+//	(error): Error from Stream
+func CartesianRules(r io.Reader, w io.Writer, file []byte, d dialect.Dialect) error {
+	lines := strings.Split(string(file), "\n")
+	return Stream(r, w, func(word string) []string { return Cartesian(word, lines) }, d)
+}
+
+// CharsToRules writes a custom rule before each character for each line of
+// a reader
+//
+// Args:
+//
+//	r (io.Reader): Lines of text
+//	w (io.Writer): Destination for the rules
+//	rule (string): String that is used in the operation
+//
+// Returns:
+//
+//	(error): ErrInvalidArgument if an argument is not valid or an error from
+//	Stream
+func CharsToRules(r io.Reader, w io.Writer, rule string) error {
+	gen, err := CharsGenerator(rule)
+	if err != nil {
+		return err
+	}
+	return Stream(r, w, gen, dialect.Hashcat{})
+}
+
+// ComboRules writes a combination of rule modes for each line of a reader
+//
+// Args:
+//
+//	r (io.Reader): Lines of text
+//	w (io.Writer): Destination for the rules
+//	modeA (string): First mode to use in the operation
+//	modeB (string): Second mode to use in the operation
+//
+// Returns:
+//
+//	(error): ErrInvalidArgument if an argument is not valid or an error from
+//	Stream
+func ComboRules(r io.Reader, w io.Writer, modeA string, modeB string) error {
+	gen, err := ComboGenerator(modeA, modeB)
+	if err != nil {
+		return err
+	}
+	return Stream(r, w, gen, dialect.Hashcat{})
+}
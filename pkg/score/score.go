@@ -0,0 +1,287 @@
+// Package score contains the logic for measuring how many known passwords
+// each rule cracks from a base wordlist
+package score
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/jakewnuk/rulecat/pkg/engine"
+)
+
+// Coverage records which plaintexts each rule cracks
+type Coverage struct {
+	// Rules are the rule lines in file order
+	Rules []string
+	// Cracks are the indexes of the plaintexts cracked by each rule
+	Cracks [][]int
+	// Plains is the number of distinct plaintexts
+	Plains int
+}
+
+// Result is the score of a single rule
+type Result struct {
+	Rule string
+	// Hits is the number of distinct plaintexts the rule cracks
+	Hits int
+	// Unique is the number of hits not cracked by any earlier rule
+	Unique int
+}
+
+// Measure applies every rule to every base word and records the plaintexts
+// each rule cracks
+//
+// # Each plaintext is counted once per rule even when several words reach it
+//
+// Args:
+//
+//	words (*bufio.Scanner): Base words as a buffer
+//	rules ([]*engine.Rule): Compiled rules in file order
+//	plains ([]string): Known plaintexts
+//
+// Returns:
+//
+//	(*Coverage): Plaintexts cracked by each rule
+func Measure(words *bufio.Scanner, rules []*engine.Rule, plains []string) *Coverage {
+	index := make(map[string]int, len(plains))
+	for _, p := range plains {
+		if _, ok := index[p]; !ok {
+			index[p] = len(index)
+		}
+	}
+
+	cracked := make([]map[int]struct{}, len(rules))
+	for i := range cracked {
+		cracked[i] = make(map[int]struct{})
+	}
+	for words.Scan() {
+		for i, r := range rules {
+			candidate, err := r.Apply(words.Text())
+			if err != nil {
+				continue
+			}
+			if p, ok := index[candidate]; ok {
+				cracked[i][p] = struct{}{}
+			}
+		}
+	}
+
+	c := &Coverage{Rules: make([]string, len(rules)), Cracks: make([][]int, len(rules)), Plains: len(index)}
+	for i, r := range rules {
+		c.Rules[i] = r.String()
+		for p := range cracked[i] {
+			c.Cracks[i] = append(c.Cracks[i], p)
+		}
+		sort.Ints(c.Cracks[i])
+	}
+	return c
+}
+
+// Score counts the hits and unique hits of each rule
+//
+// # Unique hits are counted against earlier rules in file order
+//
+// Args:
+//
+//	c (*Coverage): Plaintexts cracked by each rule
+//
+// Returns:
+//
+//	([]Result): Scores in file order
+func Score(c *Coverage) []Result {
+	covered := make([]bool, c.Plains)
+	results := make([]Result, len(c.Rules))
+	for i, rule := range c.Rules {
+		results[i] = Result{Rule: rule, Hits: len(c.Cracks[i])}
+		for _, p := range c.Cracks[i] {
+			if !covered[p] {
+				covered[p] = true
+				results[i].Unique++
+			}
+		}
+	}
+	return results
+}
+
+// ScoreRules prints the hits and unique hits of each rule as
+// hits<TAB>unique<TAB>rule
+//
+// # The threshold is compared to the sort column or to hits in file order
+//
+// Args:
+//
+//	stdIn (*bufio.Scanner): Base words as a buffer
+//	w (io.Writer): Destination for the output
+//	ruleFile ([]byte): Lines of a rule file
+//	plains ([]string): Known plaintexts
+//	sortBy (string): Sort by "hits" or "unique" in descending order or keep
+//	file order when empty
+//	threshold (int): Minimum value for a rule to be printed
+//
+// Returns:
+//
+//	None
+func ScoreRules(stdIn *bufio.Scanner, w io.Writer, ruleFile []byte, plains []string, sortBy string, threshold int) {
+	results := Score(Measure(stdIn, engine.CompileFile(ruleFile), plains))
+
+	key := func(r Result) int { return r.Hits }
+	if sortBy == "unique" {
+		key = func(r Result) int { return r.Unique }
+	}
+	if sortBy != "" {
+		sort.SliceStable(results, func(i, j int) bool { return key(results[i]) > key(results[j]) })
+	}
+
+	for _, r := range results {
+		if key(r) >= threshold {
+			fmt.Fprintf(w, "%d\t%d\t%s\n", r.Hits, r.Unique, r.Rule)
+		}
+	}
+}
+
+// Pick is a rule chosen by Order
+type Pick struct {
+	Rule string
+	// Gain is the number of plaintexts the rule adds to the coverage
+	Gain int
+	// Covered is the number of plaintexts covered after the rule
+	Covered int
+}
+
+// Order sorts rules greedily so each rule adds the most uncovered plaintexts
+//
+// # Ties keep file order and rules that add nothing follow in file order
+//
+// Args:
+//
+//	c (*Coverage): Plaintexts cracked by each rule
+//	top (int): Maximum number of rules to return or zero for all
+//
+// Returns:
+//
+//	([]Pick): Rules in greedy order
+func Order(c *Coverage, top int) []Pick {
+	if top <= 0 || top > len(c.Rules) {
+		top = len(c.Rules)
+	}
+
+	// gains only shrink so stale entries are rescored when they reach the top
+	h := &gainHeap{}
+	for i := range c.Rules {
+		h.items = append(h.items, gainItem{rule: i, gain: len(c.Cracks[i])})
+	}
+	heap.Init(h)
+
+	covered := make([]bool, c.Plains)
+	used := make([]bool, len(c.Rules))
+	total := 0
+	var picks []Pick
+	for len(picks) < top && h.Len() > 0 {
+		item := heap.Pop(h).(gainItem)
+		if item.gain == 0 {
+			break
+		}
+		gain := 0
+		for _, p := range c.Cracks[item.rule] {
+			if !covered[p] {
+				gain++
+			}
+		}
+		if gain < item.gain {
+			item.gain = gain
+			heap.Push(h, item)
+			continue
+		}
+
+		for _, p := range c.Cracks[item.rule] {
+			covered[p] = true
+		}
+		total += gain
+		used[item.rule] = true
+		picks = append(picks, Pick{Rule: c.Rules[item.rule], Gain: gain, Covered: total})
+	}
+
+	for i, rule := range c.Rules {
+		if len(picks) >= top {
+			break
+		}
+		if !used[i] {
+			picks = append(picks, Pick{Rule: rule, Covered: total})
+		}
+	}
+	return picks
+}
+
+// gainItem is a rule and the last known number of plaintexts it adds
+type gainItem struct {
+	rule int
+	gain int
+}
+
+// gainHeap is a max heap of rules by gain then file order
+type gainHeap struct {
+	items []gainItem
+}
+
+func (h *gainHeap) Len() int { return len(h.items) }
+
+func (h *gainHeap) Less(i, j int) bool {
+	if h.items[i].gain != h.items[j].gain {
+		return h.items[i].gain > h.items[j].gain
+	}
+	return h.items[i].rule < h.items[j].rule
+}
+
+func (h *gainHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *gainHeap) Push(x any) { h.items = append(h.items, x.(gainItem)) }
+
+func (h *gainHeap) Pop() any {
+	item := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return item
+}
+
+// OrderRules prints rules in greedy order and reports the cumulative coverage
+// of each rule to stderr as gain<TAB>covered<TAB>percent<TAB>rule
+//
+// Args:
+//
+//	stdIn (*bufio.Scanner): Base words as a buffer
+//	w (io.Writer): Destination for the output
+//	ruleFile ([]byte): Lines of a rule file
+//	plains ([]string): Known plaintexts
+//	top (int): Maximum number of rules to print or zero for all
+//
+// Returns:
+//
+//	None
+func OrderRules(stdIn *bufio.Scanner, w io.Writer, ruleFile []byte, plains []string, top int) {
+	c := Measure(stdIn, engine.CompileFile(ruleFile), plains)
+	picks := Order(c, top)
+
+	for _, p := range picks {
+		fmt.Fprintln(w, p.Rule)
+		if p.Gain > 0 {
+			fmt.Fprintf(os.Stderr, "%d\t%d\t%.2f%%\t%s\n", p.Gain, p.Covered, percent(p.Covered, c.Plains), p.Rule)
+		}
+	}
+
+	covered := 0
+	if len(picks) > 0 {
+		covered = picks[len(picks)-1].Covered
+	}
+	fmt.Fprintf(os.Stderr, "Covered %d of %d plaintexts (%.2f%%) with %d rules\n", covered, c.Plains, percent(covered, c.Plains), len(picks))
+}
+
+// percent returns n as a percentage of total
+func percent(n int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
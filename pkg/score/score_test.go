@@ -0,0 +1,77 @@
+package score
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/jakewnuk/rulecat/pkg/engine"
+)
+
+func TestScore(t *testing.T) {
+	words := bufio.NewScanner(strings.NewReader("pass\nword\nsummer\n"))
+	rules := engine.CompileFile([]byte("$1\n$1 $2\nc\nc $1\nu\n$1 :\n"))
+	plains := []string{"pass1", "word1", "Pass1", "Summer", "pass12", "zzz", "pass1"}
+
+	want := []Result{
+		{"$1", 2, 2},
+		{"$1 $2", 1, 1},
+		{"c", 1, 1},
+		{"c $1", 1, 1},
+		{"u", 0, 0},
+		{"$1 :", 2, 0},
+	}
+
+	got := Score(Measure(words, rules, plains))
+	if len(got) != len(want) {
+		t.Fatalf("Score() returned %d results; want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Score()[%d] = %+v; want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	words := bufio.NewScanner(strings.NewReader("pass\nPASS\npass\n"))
+	rules := engine.CompileFile([]byte("l $1\n"))
+	c := Measure(words, rules, []string{"zzz", "pass1"})
+
+	if c.Plains != 2 {
+		t.Errorf("Measure().Plains = %d; want 2", c.Plains)
+	}
+	if len(c.Cracks[0]) != 1 || c.Cracks[0][0] != 1 {
+		t.Errorf("Measure().Cracks[0] = %v; want [1]", c.Cracks[0])
+	}
+}
+
+func TestOrder(t *testing.T) {
+	c := &Coverage{
+		Rules:  []string{"a", "b", "c", "d", "e"},
+		Cracks: [][]int{{0, 1}, {0, 1, 2}, {3, 4}, {}, {2, 3}},
+		Plains: 5,
+	}
+
+	tests := []struct {
+		top  int
+		want []Pick
+	}{
+		{0, []Pick{{"b", 3, 3}, {"c", 2, 5}, {"a", 0, 5}, {"d", 0, 5}, {"e", 0, 5}}},
+		{1, []Pick{{"b", 3, 3}}},
+		{3, []Pick{{"b", 3, 3}, {"c", 2, 5}, {"a", 0, 5}}},
+	}
+
+	for _, test := range tests {
+		got := Order(c, test.top)
+		if len(got) != len(test.want) {
+			t.Errorf("Order(%d) = %+v; want %+v", test.top, got, test.want)
+			continue
+		}
+		for i := range test.want {
+			if got[i] != test.want[i] {
+				t.Errorf("Order(%d)[%d] = %+v; want %+v", test.top, i, got[i], test.want[i])
+			}
+		}
+	}
+}
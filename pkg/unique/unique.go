@@ -0,0 +1,163 @@
+// Package unique contains the logic for removing repeated lines from output
+package unique
+
+import (
+	"bytes"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"math"
+)
+
+// Filter remembers lines to find repeats
+type Filter interface {
+	// Seen reports if a line was added before and adds it
+	Seen(line string) bool
+}
+
+// Exact is a Filter that keeps every line in memory
+type Exact map[string]struct{}
+
+// NewExact creates a Filter without false positives
+//
+// Returns:
+//
+//	(Exact): Empty filter
+func NewExact() Exact {
+	return make(Exact)
+}
+
+// Seen reports if a line was added before and adds it
+func (e Exact) Seen(line string) bool {
+	if _, ok := e[line]; ok {
+		return true
+	}
+	e[line] = struct{}{}
+	return false
+}
+
+// Bloom is a Filter with a fixed memory size that can report a new line as
+// seen with a configured false positive rate
+type Bloom struct {
+	bits []uint64
+	// size is the number of bits in the filter
+	size uint64
+	// hashes is the number of bits set for each line
+	hashes uint64
+	seeds  [2]maphash.Seed
+}
+
+// NewBloom creates a Bloom filter sized for a number of lines
+//
+// # The false positive rate rises above the target once more lines than
+// the capacity are added
+//
+// Args:
+//
+//	capacity (uint64): Expected number of unique lines
+//	rate (float64): Target false positive rate between 0 and 1
+//
+// Returns:
+//
+//	(*Bloom): Empty filter
+//	(error): Error if the capacity or rate is out of range
+func NewBloom(capacity uint64, rate float64) (*Bloom, error) {
+	if capacity == 0 {
+		return nil, fmt.Errorf("capacity must be at least 1")
+	}
+	if rate <= 0 || rate >= 1 {
+		return nil, fmt.Errorf("false positive rate %g must be between 0 and 1", rate)
+	}
+
+	size := uint64(math.Ceil(-float64(capacity) * math.Log(rate) / (math.Ln2 * math.Ln2)))
+	hashes := uint64(math.Max(1, math.Round(float64(size)/float64(capacity)*math.Ln2)))
+	return &Bloom{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+		seeds:  [2]maphash.Seed{maphash.MakeSeed(), maphash.MakeSeed()},
+	}, nil
+}
+
+// Seen reports if a line was probably added before and adds it
+func (b *Bloom) Seen(line string) bool {
+	h1 := maphash.String(b.seeds[0], line)
+	h2 := maphash.String(b.seeds[1], line) | 1
+
+	seen := true
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.size
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if b.bits[word]&mask == 0 {
+			seen = false
+			b.bits[word] |= mask
+		}
+	}
+	return seen
+}
+
+// Writer is an io.Writer that drops lines that were already written
+type Writer struct {
+	w      io.Writer
+	filter Filter
+	// partial is the start of a line that has no newline yet
+	partial []byte
+	out     []byte
+}
+
+// NewWriter creates a Writer that removes repeated lines
+//
+// Args:
+//
+//	w (io.Writer): Destination for the new lines
+//	filter (Filter): Filter to find repeated lines
+//
+// Returns:
+//
+//	(*Writer): Writer that must be flushed after the last write
+func NewWriter(w io.Writer, filter Filter) *Writer {
+	return &Writer{w: w, filter: filter}
+}
+
+// Write writes each complete line that was not seen before
+func (u *Writer) Write(p []byte) (int, error) {
+	u.out = u.out[:0]
+	rest := p
+	for {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break
+		}
+		line := rest[:i+1]
+		rest = rest[i+1:]
+		if len(u.partial) > 0 {
+			line = append(u.partial, line...)
+			u.partial = u.partial[:0]
+		}
+		if !u.filter.Seen(string(line[:len(line)-1])) {
+			u.out = append(u.out, line...)
+		}
+	}
+	u.partial = append(u.partial, rest...)
+
+	if len(u.out) > 0 {
+		if _, err := u.w.Write(u.out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes the last line if it has no newline and was not seen before
+func (u *Writer) Flush() error {
+	if len(u.partial) == 0 {
+		return nil
+	}
+	line := u.partial
+	u.partial = nil
+	if u.filter.Seen(string(line)) {
+		return nil
+	}
+	_, err := u.w.Write(line)
+	return err
+}
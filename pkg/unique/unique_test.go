@@ -0,0 +1,70 @@
+package unique
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{"lines", []string{"$1\n$2\n$1\n"}, "$1\n$2\n"},
+		{"split lines", []string{"$1\n$", "2\n$1", "\n$2\n"}, "$1\n$2\n"},
+		{"no newline", []string{"$1\n$3\n$3"}, "$1\n$3\n"},
+		{"last line", []string{"$1\n$4"}, "$1\n$4"},
+		{"blank lines", []string{"\n\n$1\n\n"}, "\n$1\n"},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		w := NewWriter(&out, NewExact())
+		for _, s := range test.writes {
+			if n, err := w.Write([]byte(s)); err != nil || n != len(s) {
+				t.Fatalf("%s: Write(%q) = %d, %v", test.name, s, n, err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("%s: Flush() error = %v", test.name, err)
+		}
+		if out.String() != test.want {
+			t.Errorf("%s: wrote %q; want %q", test.name, out.String(), test.want)
+		}
+	}
+}
+
+func TestBloom(t *testing.T) {
+	if _, err := NewBloom(10, 0); err == nil {
+		t.Errorf("NewBloom(10, 0) error = nil; want an error")
+	}
+	if _, err := NewBloom(0, 0.01); err == nil {
+		t.Errorf("NewBloom(0, 0.01) error = nil; want an error")
+	}
+
+	b, err := NewBloom(10000, 0.01)
+	if err != nil {
+		t.Fatalf("NewBloom() error = %v", err)
+	}
+	for i := 0; i < 10000; i++ {
+		b.Seen(fmt.Sprintf("$%d", i))
+	}
+	for i := 0; i < 10000; i++ {
+		if !b.Seen(fmt.Sprintf("$%d", i)) {
+			t.Fatalf("Seen(%q) = false after it was added", fmt.Sprintf("$%d", i))
+		}
+	}
+
+	// probes are added too so only a few are checked to keep the fill steady
+	falsePositives := 0
+	for i := 0; i < 1000; i++ {
+		if b.Seen(fmt.Sprintf("^%d", i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 30 {
+		t.Errorf("Bloom reported %d of 1000 new lines as seen; want about 10", falsePositives)
+	}
+}
@@ -2,6 +2,7 @@
 package utils
 
 import (
+	"encoding/hex"
 	"fmt"
 	"strings"
 	"unicode"
@@ -95,11 +96,114 @@ func StringToToggle(str string, rule string, index int) string {
 //
 //	(string): Transformed string
 func ReverseString(s string) string {
-	runes := []rune(s)
-	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
-		runes[i], runes[j] = runes[j], runes[i]
+	// characters are moved as whole UTF-8 sequences and invalid bytes are
+	// kept as they are
+	reversed := make([]byte, 0, len(s))
+	for end := len(s); end > 0; {
+		_, size := utf8.DecodeLastRuneInString(s[:end])
+		reversed = append(reversed, s[end-size:end]...)
+		end -= size
 	}
-	return string(runes)
+	return string(reversed)
+}
+
+// SplitLines splits file contents into non-empty lines
+//
+// Args:
+//
+//	file ([]byte): File contents
+//
+// Returns:
+//
+//	([]string): Lines without line endings
+func SplitLines(file []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(file), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// DecodeHexString decodes text in the Hashcat $HEX[...] format
+//
+// # Text that is not in the format or has invalid hex is returned unchanged
+//
+// Args:
+//
+//	str (string): Input string such as $HEX[70617373]
+//
+// Returns:
+//
+//	(string): Decoded string
+//	(bool): If the string was decoded
+func DecodeHexString(str string) (string, bool) {
+	if !strings.HasPrefix(str, "$HEX[") || !strings.HasSuffix(str, "]") {
+		return str, false
+	}
+	decoded, err := hex.DecodeString(str[len("$HEX[") : len(str)-1])
+	if err != nil {
+		return str, false
+	}
+	return string(decoded), true
+}
+
+// EncodeHexString encodes text in the Hashcat $HEX[...] format
+//
+// Args:
+//
+//	str (string): Input string to encode
+//
+// Returns:
+//
+//	(string): Encoded string
+func EncodeHexString(str string) string {
+	return "$HEX[" + hex.EncodeToString([]byte(str)) + "]"
+}
+
+// CheckPrintableString checks to see if a string can be written as plain text
+//
+// # Strings with control characters, invalid UTF-8, or that are already in
+// the $HEX[...] format are not printable
+//
+// Args:
+//
+//	str (string): Input string to check
+//
+// Returns:
+//
+//	(bool): If the string can be written without $HEX[...]
+func CheckPrintableString(str string) bool {
+	if !utf8.ValidString(str) {
+		return false
+	}
+	if _, ok := DecodeHexString(str); ok {
+		return false
+	}
+	for _, r := range str {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatPlaintext writes a string in the $HEX[...] format if it is not
+// printable
+//
+// Args:
+//
+//	str (string): Input string to format
+//
+// Returns:
+//
+//	(string): String or its $HEX[...] encoding
+func FormatPlaintext(str string) string {
+	if CheckPrintableString(str) {
+		return str
+	}
+	return EncodeHexString(str)
 }
 
 // CheckASCIIString checks to see if a string only contains ascii characters
@@ -118,6 +222,16 @@ func CheckASCIIString(str string) bool {
 	return true
 }
 
+// checkPrintableASCII checks if every byte of a string is printable ASCII
+func checkPrintableASCII(str string) bool {
+	for i := 0; i < len(str); i++ {
+		if str[i] < 32 || str[i] > 126 {
+			return false
+		}
+	}
+	return true
+}
+
 // PrintCharacterRuleOutput handles printing the rules to the CLI
 //
 // prints for CharToRule functions
@@ -130,9 +244,26 @@ func CheckASCIIString(str string) bool {
 //
 // None
 func PrintCharacterRuleOutput(strs ...string) {
+	if output := FormatCharacterRuleOutput(strs...); output != "" {
+		fmt.Println(output)
+	}
+}
+
+// FormatCharacterRuleOutput joins rules and converts multibyte characters
+//
+// formats for CharToRule functions
+//
+// Args:
+//
+//	strs (...string): Input strings to join
+//
+// Returns:
+//
+//	(string): Joined rule or an empty string if it is over 93 characters
+func FormatCharacterRuleOutput(strs ...string) string {
 	output := ""
 	for _, str := range strs {
-		if CheckASCIIString(str) {
+		if checkPrintableASCII(str) {
 			output += str + " "
 		} else {
 			output += ConvertCharacterMultiByteString(str)
@@ -140,8 +271,9 @@ func PrintCharacterRuleOutput(strs ...string) {
 	}
 
 	if output != "" && len(output) <= 93 {
-		fmt.Println(strings.TrimSpace(output))
+		return strings.TrimSpace(output)
 	}
+	return ""
 }
 
 // ConvertCharacterMultiByteString converts non-ascii characters to a hashcat valid format
@@ -158,34 +290,37 @@ func PrintCharacterRuleOutput(strs ...string) {
 func ConvertCharacterMultiByteString(str string) string {
 	returnStr := ""
 	deletedChar := ``
-	for i, r := range str {
-		if r > 127 {
+	for i := 0; i < len(str); {
+		r, size := utf8.DecodeRuneInString(str[i:])
+		// invalid UTF-8 is converted byte by byte as it was read
+		byteArr := []byte(str[i : i+size])
+		if r > 127 || r < 32 || r == 127 {
 			if i > 0 {
 				deletedChar = string(returnStr[len(returnStr)-1])
 				returnStr = returnStr[:len(returnStr)-1]
 			}
-			byteArr := []byte(string(r))
 			if deletedChar == "^" {
 				for j := len(byteArr) - 1; j >= 0; j-- {
 					b := byteArr[j]
 					if j == 0 {
-						returnStr += fmt.Sprintf("%s\\x%X", deletedChar, b)
+						returnStr += fmt.Sprintf("%s\\x%02X", deletedChar, b)
 					} else {
-						returnStr += fmt.Sprintf("%s\\x%X ", deletedChar, b)
+						returnStr += fmt.Sprintf("%s\\x%02X ", deletedChar, b)
 					}
 				}
 			} else {
 				for j, b := range byteArr {
 					if j == len(byteArr)-1 {
-						returnStr += fmt.Sprintf("%s\\x%X", deletedChar, b)
+						returnStr += fmt.Sprintf("%s\\x%02X", deletedChar, b)
 					} else {
-						returnStr += fmt.Sprintf("%s\\x%X ", deletedChar, b)
+						returnStr += fmt.Sprintf("%s\\x%02X ", deletedChar, b)
 					}
 				}
 			}
 		} else {
-			returnStr += fmt.Sprintf("%c", r)
+			returnStr += string(byteArr)
 		}
+		i += size
 	}
 	return returnStr
 }
@@ -83,6 +83,8 @@ func TestReverseString(t *testing.T) {
 	}{
 		{"hello", "olleh"},
 		{"world", "dlrow"},
+		{"a世b", "b世a"},
+		{"\xfcni", "in\xfc"},
 	}
 
 	for _, test := range tests {
@@ -137,6 +139,11 @@ func TestConvertCharacterMultiByteString(t *testing.T) {
 			str:  "$H $e $l $l $o $  $世 $界 $!",
 			want: "$H $e $l $l $o $  $\\xE4 $\\xB8 $\\x96 $\\xE7 $\\x95 $\\x8C $!",
 		},
+		{
+			name: "Contains control and invalid characters",
+			str:  "$\t $a $\xfc",
+			want: "$\\x09 $a $\\xFC",
+		},
 		{
 			name: "Contains non-ASCII character with ^",
 			str:  "^! ^界 ^世 ^  ^o ^l ^l ^e ^H",
@@ -153,3 +160,43 @@ func TestConvertCharacterMultiByteString(t *testing.T) {
 		})
 	}
 }
+
+func TestHexString(t *testing.T) {
+	tests := []struct {
+		str       string
+		want      string
+		decoded   bool
+		printable bool
+	}{
+		{"$HEX[70617373]", "pass", true, false},
+		{"$HEX[FC6e69]", "\xfcni", true, false},
+		{"$HEX[]", "", true, false},
+		{"$HEX[7]", "$HEX[7]", false, true},
+		{"$HEX[zz]", "$HEX[zz]", false, true},
+		{"pass", "pass", false, true},
+		{"Ünï", "Ünï", false, true},
+		{"a\tb", "a\tb", false, false},
+	}
+
+	for _, test := range tests {
+		got, ok := DecodeHexString(test.str)
+		if got != test.want || ok != test.decoded {
+			t.Errorf("DecodeHexString(%q) = %q, %v; want %q, %v", test.str, got, ok, test.want, test.decoded)
+		}
+		if printable := CheckPrintableString(test.str); printable != test.printable {
+			t.Errorf("CheckPrintableString(%q) = %v; want %v", test.str, printable, test.printable)
+		}
+	}
+
+	formats := map[string]string{
+		"pass":   "pass",
+		"\xfcni": "$HEX[fc6e69]",
+		"a\tb":   "$HEX[610962]",
+		"$HEX[]": "$HEX[244845585b5d]",
+	}
+	for str, want := range formats {
+		if got := FormatPlaintext(str); got != want {
+			t.Errorf("FormatPlaintext(%q) = %q; want %q", str, got, want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Package validate contains the logic for linting Hashcat rule files for
+// Hashcat and John the Ripper
+package validate
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jakewnuk/rulecat/pkg/dialect"
+	"github.com/jakewnuk/rulecat/pkg/parser"
+)
+
+// MaxFunctions is the maximum number of functions Hashcat allows per rule
+const MaxFunctions = 31
+
+// MaxRuleLength is the maximum length of a rule line Hashcat will load
+const MaxRuleLength = 255
+
+// gpuUnsupported are memory functions that Hashcat cannot run on GPU
+var gpuUnsupported = map[byte]bool{'X': true, '4': true, '6': true, 'M': true, 'Q': true}
+
+// rejections are functions that Hashcat only supports with -j and -k
+var rejections = map[byte]bool{
+	'<': true, '>': true, '_': true, '!': true, '/': true, '(': true,
+	')': true, '=': true, '%': true,
+}
+
+// Diagnostic describes a problem found on a rule line
+type Diagnostic struct {
+	// Line is the 1-based line number in the input
+	Line int
+	// Column is the 1-based column of the offending function
+	Column int
+	// Opcode is the offending function or zero if the whole line is at fault
+	Opcode byte
+	// Reason describes the problem
+	Reason string
+}
+
+// String formats the diagnostic for printing
+func (d Diagnostic) String() string {
+	if d.Opcode == 0 {
+		return fmt.Sprintf("line %d: %s", d.Line, d.Reason)
+	}
+	return fmt.Sprintf("line %d: column %d: %q: %s", d.Line, d.Column, d.Opcode, d.Reason)
+}
+
+// CheckRule checks a single rule line against Hashcat limits
+//
+// # The Line field of returned diagnostics is left as zero
+//
+// Args:
+//
+//	rule (string): Rule line to check
+//
+// Returns:
+//
+//	([]Diagnostic): Problems found on the line
+func CheckRule(rule string) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	if len(rule) > MaxRuleLength {
+		diagnostics = append(diagnostics, Diagnostic{
+			Reason: fmt.Sprintf("rule is %d characters, maximum is %d", len(rule), MaxRuleLength),
+		})
+	}
+
+	ops, err := parser.Parse(rule)
+	if err != nil {
+		return append(diagnostics, parseDiagnostics(err)...)
+	}
+
+	if len(ops) > MaxFunctions {
+		diagnostics = append(diagnostics, Diagnostic{
+			Column: ops[MaxFunctions].Column,
+			Opcode: ops[MaxFunctions].Opcode,
+			Reason: fmt.Sprintf("rule has %d functions, maximum is %d", len(ops), MaxFunctions),
+		})
+	}
+
+	for _, op := range ops {
+		if gpuUnsupported[op.Opcode] {
+			diagnostics = append(diagnostics, Diagnostic{Column: op.Column, Opcode: op.Opcode, Reason: "memory function is not supported on GPU"})
+		} else if rejections[op.Opcode] {
+			diagnostics = append(diagnostics, Diagnostic{Column: op.Column, Opcode: op.Opcode, Reason: "rejection function is only supported with -j or -k"})
+		}
+	}
+	return diagnostics
+}
+
+// CheckJohn checks that a single Hashcat rule line can be used with John the
+// Ripper
+//
+// # Each function must have an equivalent that convert can write. The Line
+// field of returned diagnostics is left as zero
+//
+// Args:
+//
+//	rule (string): Rule line to check
+//
+// Returns:
+//
+//	([]Diagnostic): Problems found on the line
+func CheckJohn(rule string) []Diagnostic {
+	ops, err := parser.Parse(rule)
+	if err != nil {
+		return parseDiagnostics(err)
+	}
+
+	var diagnostics []Diagnostic
+	for _, op := range ops {
+		if _, err := (dialect.John{}).Render(op.String()); err != nil {
+			diagnostics = append(diagnostics, Diagnostic{Column: op.Column, Opcode: op.Opcode, Reason: "function has no equivalent in John the Ripper"})
+		}
+	}
+	return diagnostics
+}
+
+// parseDiagnostics converts a parse error into a diagnostic
+func parseDiagnostics(err error) []Diagnostic {
+	var perr *parser.Error
+	if errors.As(err, &perr) {
+		return []Diagnostic{{Column: perr.Column, Opcode: perr.Opcode, Reason: perr.Reason}}
+	}
+	return nil
+}
+
+// ValidateRules checks each rule line from input and reports problems
+//
+// # Empty lines and lines starting with # are skipped
+//
+// Args:
+//
+//	stdIn (*bufio.Scanner): Rule lines as a buffer
+//	w (io.Writer): Destination for the output
+//	clean (bool): Print only valid lines and send diagnostics to stderr
+//	check (func(string) []Diagnostic): Check to run on each line such as
+//	CheckRule or CheckJohn
+//
+// Returns:
+//
+//	(int): Number of lines with problems
+func ValidateRules(stdIn *bufio.Scanner, w io.Writer, clean bool, check func(rule string) []Diagnostic) int {
+	invalid := 0
+	line := 0
+	for stdIn.Scan() {
+		line++
+		text := strings.TrimRight(stdIn.Text(), "\r")
+		if text == "" || strings.HasPrefix(text, "#") {
+			if clean {
+				fmt.Fprintln(w, text)
+			}
+			continue
+		}
+
+		diagnostics := check(text)
+		if len(diagnostics) == 0 {
+			if clean {
+				fmt.Fprintln(w, text)
+			}
+			continue
+		}
+
+		invalid++
+		for _, d := range diagnostics {
+			d.Line = line
+			if clean {
+				fmt.Fprintln(os.Stderr, d.String())
+			} else {
+				fmt.Fprintln(w, d.String())
+			}
+		}
+	}
+	return invalid
+}
@@ -0,0 +1,66 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		opcodes []byte
+	}{
+		{"Valid rule", "$1 $2 $3 c", nil},
+		{"Unknown function", "$1 w", []byte{'w'}},
+		{"Invalid position", "Tz", []byte{'T'}},
+		{"Missing argument", "$", []byte{'$'}},
+		{"Memory functions", "M $1 4", []byte{'M', '4'}},
+		{"Rejection function", "<8 $1", []byte{'<'}},
+		{"Too many functions", strings.Repeat("$1 ", MaxFunctions+1), []byte{'$'}},
+		{"Too long", strings.Repeat(":", MaxRuleLength+1), []byte{0, ':'}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckRule(tt.rule)
+			if len(got) != len(tt.opcodes) {
+				t.Fatalf("CheckRule(%q) = %v, want %d diagnostics", tt.rule, got, len(tt.opcodes))
+			}
+			for i, d := range got {
+				if d.Opcode != tt.opcodes[i] {
+					t.Errorf("CheckRule(%q)[%d] opcode = %q, want %q", tt.rule, i, d.Opcode, tt.opcodes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckJohn(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		opcodes []byte
+	}{
+		{"Valid rule", "$1 $2 $3 c", nil},
+		{"Rejection and brackets", "<8 [ ] p1", nil},
+		{"Unknown function", "$1 w", []byte{'w'}},
+		{"Memory functions", "M $1 4", []byte{'M', '4'}},
+		{"Hashcat only functions", "k p2 <Z", []byte{'k', 'p', '<'}},
+		{"Too many functions", strings.Repeat("$1 ", MaxFunctions+1), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckJohn(tt.rule)
+			if len(got) != len(tt.opcodes) {
+				t.Fatalf("CheckJohn(%q) = %v, want %d diagnostics", tt.rule, got, len(tt.opcodes))
+			}
+			for i, d := range got {
+				if d.Opcode != tt.opcodes[i] {
+					t.Errorf("CheckJohn(%q)[%d] opcode = %q, want %q", tt.rule, i, d.Opcode, tt.opcodes[i])
+				}
+			}
+		})
+	}
+}